@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/models"
+)
+
+// userContextKey is a key of the authenticated user on echo.Context
+const userContextKey = "auth.user"
+
+// SetUser is a function to store the authenticated user on the request context
+func SetUser(c echo.Context, user models.User) {
+	c.Set(userContextKey, user)
+}
+
+// CurrentUser is a function to get the authenticated user from the request context
+func CurrentUser(c echo.Context) (models.User, bool) {
+	user, ok := c.Get(userContextKey).(models.User)
+	return user, ok
+}
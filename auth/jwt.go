@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// leeway is a clock skew tolerated when checking exp and nbf
+const leeway = 30 * time.Second
+
+var (
+	// ErrInvalidToken is an error for a token that is malformed or has a bad signature
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is an error for a token used after exp or before nbf
+	ErrTokenExpired = errors.New("token is expired or not yet valid")
+	// ErrInvalidClaims is an error for a token with an unexpected issuer or audience
+	ErrInvalidClaims = errors.New("invalid token claims")
+)
+
+// Claims is a struct for the verified claims of a token
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Roles     []string `json:"roles"`
+}
+
+// audience is a type for the aud claim which is either a string or a list of strings
+type audience []string
+
+// UnmarshalJSON is a function to read an aud claim
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Verifier is a struct for verifying signed JWTs
+type Verifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewVerifier is a function to create new verifier, an empty issuer or audience is not checked
+func NewVerifier(keys *KeySet, issuer string, audience string) *Verifier {
+	return &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		now:      time.Now,
+	}
+}
+
+// LooksLikeJWT is a function to check if a credential has the three part shape of a JWT
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify is a function to check the signature and registered claims of a token
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	key, err := v.keys.Key(header.Kid)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	return claims, v.validate(claims)
+}
+
+// validate is a function to check the time, issuer and audience claims
+func (v *Verifier) validate(claims Claims) error {
+	now := v.now()
+
+	if claims.ExpiresAt == nil || now.After(unixTime(*claims.ExpiresAt).Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(leeway).Before(unixTime(*claims.NotBefore)) {
+		return ErrTokenExpired
+	}
+	if claims.Subject == "" {
+		return ErrInvalidClaims
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return ErrInvalidClaims
+	}
+	if v.audience != "" && !contains(claims.Audience, v.audience) {
+		return ErrInvalidClaims
+	}
+
+	return nil
+}
+
+// verifySignature is a function to verify a signature with a key of the type required by alg
+func verifySignature(alg string, key interface{}, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrInvalidToken
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrInvalidToken
+		}
+	case "RS256":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) != nil {
+			return ErrInvalidToken
+		}
+	case "ES256":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return ErrInvalidToken
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return ErrInvalidToken
+		}
+	default:
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// decodeSegment is a function to decode a base64url JSON segment of a token
+func decodeSegment(segment string, value interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, value)
+}
+
+// unixTime is a function to convert a NumericDate claim into a time
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// contains is a function to check if a list holds a value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
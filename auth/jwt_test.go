@@ -0,0 +1,266 @@
+//go:build unit
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(t *testing.T, alg string, kid string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case "RS256":
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "alice",
+		"iss":   "https://issuer.test",
+		"aud":   []string{"expenses"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"editor"},
+	}
+}
+
+func staticKeys(keys map[string]interface{}) *KeySet {
+	keySet, _ := NewKeySet(func() (map[string]interface{}, error) { return keys, nil })
+	return keySet
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("top secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	verifier := NewVerifier(staticKeys(map[string]interface{}{
+		"hs": secret,
+		"rs": &rsaKey.PublicKey,
+		"es": &ecKey.PublicKey,
+	}), "https://issuer.test", "expenses")
+
+	subtests := []struct {
+		name        string
+		token       func() string
+		expectedErr error
+	}{
+		{name: "HS256", token: func() string { return sign(t, "HS256", "hs", secret, validClaims()) }},
+		{name: "RS256", token: func() string { return sign(t, "RS256", "rs", rsaKey, validClaims()) }},
+		{name: "ES256", token: func() string { return sign(t, "ES256", "es", ecKey, validClaims()) }},
+		{
+			name:        "wrong secret",
+			token:       func() string { return sign(t, "HS256", "hs", []byte("guess"), validClaims()) },
+			expectedErr: ErrInvalidToken,
+		},
+		{
+			name:        "algorithm does not match key",
+			token:       func() string { return sign(t, "HS256", "rs", secret, validClaims()) },
+			expectedErr: ErrInvalidToken,
+		},
+		{
+			name:        "unknown kid",
+			token:       func() string { return sign(t, "HS256", "other", secret, validClaims()) },
+			expectedErr: ErrInvalidToken,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return sign(t, "HS256", "hs", secret, claims)
+			},
+			expectedErr: ErrTokenExpired,
+		},
+		{
+			name: "not yet valid",
+			token: func() string {
+				claims := validClaims()
+				claims["nbf"] = time.Now().Add(time.Hour).Unix()
+				return sign(t, "HS256", "hs", secret, claims)
+			},
+			expectedErr: ErrTokenExpired,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://evil.test"
+				return sign(t, "HS256", "hs", secret, claims)
+			},
+			expectedErr: ErrInvalidClaims,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = "reports"
+				return sign(t, "HS256", "hs", secret, claims)
+			},
+			expectedErr: ErrInvalidClaims,
+		},
+	}
+	for _, subtest := range subtests {
+		t.Run(subtest.name, func(t *testing.T) {
+			claims, err := verifier.Verify(subtest.token())
+			if !errors.Is(err, subtest.expectedErr) {
+				t.Errorf("expected error (%v), got error (%v)", subtest.expectedErr, err)
+			}
+			if subtest.expectedErr == nil {
+				assert.Equal(t, "alice", claims.Subject)
+				assert.Equal(t, []string{"editor"}, claims.Roles)
+			}
+		})
+	}
+}
+
+func TestJWKSKeyRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS := func(kid string, key *ecdsa.PublicKey) {
+		encode := func(value *big.Int) string {
+			return base64.RawURLEncoding.EncodeToString(value.FillBytes(make([]byte, 32)))
+		}
+		content := fmt.Sprintf(`{"keys":[{"kty":"EC","crv":"P-256","kid":%q,"use":"sig","x":%q,"y":%q}]}`, kid, encode(key.X), encode(key.Y))
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	writeJWKS("2022-01", &oldKey.PublicKey)
+
+	keys, err := NewKeySet(JWKSFileLoader(path))
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when loading keys", err)
+	}
+	verifier := NewVerifier(keys, "", "")
+
+	_, err = verifier.Verify(sign(t, "ES256", "2022-01", oldKey, validClaims()))
+	assert.NoError(t, err)
+
+	writeJWKS("2022-02", &newKey.PublicKey)
+	keys.loadedAt = time.Now().Add(-reloadInterval)
+
+	_, err = verifier.Verify(sign(t, "ES256", "2022-02", newKey, validClaims()))
+	assert.NoError(t, err, "a new kid should reload the key set")
+}
+
+func TestParseJWKSRejectsWeakKeys(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	encode := func(value *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(value.FillBytes(make([]byte, 32)))
+	}
+	offCurveY := new(big.Int).Add(key.Y, big.NewInt(1))
+
+	tests := []struct {
+		name string
+		jwk  string
+		err  string
+	}{
+		{
+			name: "point on curve",
+			jwk:  fmt.Sprintf(`{"kty":"EC","crv":"P-256","kid":"ec-1","x":%q,"y":%q}`, encode(key.X), encode(key.Y)),
+		},
+		{
+			name: "point off curve",
+			jwk:  fmt.Sprintf(`{"kty":"EC","crv":"P-256","kid":"ec-1","x":%q,"y":%q}`, encode(key.X), encode(offCurveY)),
+			err:  `jwks key "ec-1": point is not on curve P-256`,
+		},
+		{
+			name: "secret of 32 bytes",
+			jwk:  fmt.Sprintf(`{"kty":"oct","kid":"hmac-1","k":%q}`, base64.RawURLEncoding.EncodeToString(make([]byte, 32))),
+		},
+		{
+			name: "short secret",
+			jwk:  fmt.Sprintf(`{"kty":"oct","kid":"hmac-1","k":%q}`, base64.RawURLEncoding.EncodeToString([]byte("top secret"))),
+			err:  `jwks key "hmac-1": secret is shorter than 32 bytes`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, err := ParseJWKS([]byte(`{"keys":[` + test.jwk + `]}`))
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Len(t, keys, 1)
+			}
+		})
+	}
+}
+
+func TestPEMDirLoader(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	os.WriteFile(filepath.Join(dir, "rsa-1.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+	os.WriteFile(filepath.Join(dir, "hmac-1.secret"), []byte("top secret\n"), 0o600)
+	os.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0o600)
+
+	keys, err := PEMDirLoader(dir)()
+
+	if assert.NoError(t, err) {
+		assert.Len(t, keys, 2)
+		assert.Equal(t, []byte("top secret"), keys["hmac-1"])
+		assert.Equal(t, &rsaKey.PublicKey, keys["rsa-1"])
+	}
+}
+
+func TestParsePEMRejectsCurvesOtherThanP256(t *testing.T) {
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(&p256Key.PublicKey)
+
+	key, err := ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, &p256Key.PublicKey, key)
+	}
+
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	der, _ = x509.MarshalPKIXPublicKey(&p384Key.PublicKey)
+
+	_, err = ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.EqualError(t, err, `unsupported curve "P-384"`)
+}
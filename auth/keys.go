@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reloadInterval is a minimum time between key reloads triggered by an unknown kid
+const reloadInterval = 10 * time.Second
+
+// minOctKeyBytes is a minimum size of an HMAC secret given as a JSON web key, HS256 needs at least its hash size
+const minOctKeyBytes = 32
+
+// ErrUnknownKey is an error for a token signed by a key that is not in the key set
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeyLoader is a function that load verification keys by kid,
+// values are *rsa.PublicKey, *ecdsa.PublicKey or []byte HMAC secrets
+type KeyLoader func() (map[string]interface{}, error)
+
+// KeySet is a struct for verification keys that reloads when a token names an unknown kid
+type KeySet struct {
+	mu       sync.RWMutex
+	load     KeyLoader
+	keys     map[string]interface{}
+	loadedAt time.Time
+}
+
+// NewKeySet is a function to create new key set and load its keys
+func NewKeySet(load KeyLoader) (*KeySet, error) {
+	keys, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySet{
+		load:     load,
+		keys:     keys,
+		loadedAt: time.Now(),
+	}, nil
+}
+
+// Key is a function to get a key by kid, an empty kid matches the only key of a single key set
+func (k *KeySet) Key(kid string) (interface{}, error) {
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+
+	// a new kid usually means the keys were rotated so reload them, but not on every bad token
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if time.Since(k.loadedAt) >= reloadInterval {
+		keys, err := k.load()
+		k.loadedAt = time.Now()
+		if err != nil {
+			return nil, err
+		}
+		k.keys = keys
+	}
+
+	if key, ok := find(k.keys, kid); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// lookup is a function to find a key under the read lock
+func (k *KeySet) lookup(kid string) (interface{}, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return find(k.keys, kid)
+}
+
+// find is a function to find a key by kid in a key map
+func find(keys map[string]interface{}, kid string) (interface{}, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// jsonWebKey is a struct for a key of a JWKS document
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// JWKSFileLoader is a function to create a key loader reading a JWKS file
+func JWKSFileLoader(path string) KeyLoader {
+	return func() (map[string]interface{}, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return ParseJWKS(content)
+	}
+}
+
+// ParseJWKS is a function to parse the signature keys of a JWKS document
+func ParseJWKS(content []byte) (map[string]interface{}, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.key()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// key is a function to convert a JSON web key into a verification key
+func (jwk jsonWebKey) key() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		// a point off the curve would leave signature checks to the arithmetic of an invalid key
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(jwk.K, "="))
+		if err != nil {
+			return nil, err
+		}
+		if len(secret) < minOctKeyBytes {
+			return nil, fmt.Errorf("secret is shorter than %d bytes", minOctKeyBytes)
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// decodeBigInt is a function to decode a base64url big endian integer
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// PEMDirLoader is a function to create a key loader reading a directory where each file name is a kid,
+// <kid>.pem holds an RSA or EC public key or certificate and <kid>.secret holds an HMAC secret
+func PEMDirLoader(dir string) KeyLoader {
+	return func() (map[string]interface{}, error) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := map[string]interface{}{}
+		for _, entry := range entries {
+			extension := filepath.Ext(entry.Name())
+			kid := strings.TrimSuffix(entry.Name(), extension)
+			if entry.IsDir() || (extension != ".pem" && extension != ".secret") {
+				continue
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			if extension == ".secret" {
+				keys[kid] = []byte(strings.TrimSpace(string(content)))
+				continue
+			}
+
+			key, err := ParsePEM(content)
+			if err != nil {
+				return nil, fmt.Errorf("key file %q: %w", entry.Name(), err)
+			}
+			keys[kid] = key
+		}
+
+		return keys, nil
+	}
+}
+
+// ParsePEM is a function to parse a PEM encoded public key or certificate,
+// EC keys must be on P-256 as only ES256 tokens are verified with them
+func ParsePEM(content []byte) (interface{}, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var certificate *x509.Certificate
+		certificate, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = certificate.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ecKey, ok := key.(*ecdsa.PublicKey); ok && ecKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("unsupported curve %q", ecKey.Curve.Params().Name)
+	}
+
+	return key, nil
+}
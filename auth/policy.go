@@ -0,0 +1,118 @@
+package auth
+
+import "strings"
+
+// Permission is a type for an operation a role may be granted
+type Permission string
+
+const (
+	// PermissionRead is a permission to list and show expenses
+	PermissionRead Permission = "expenses:read"
+	// PermissionWrite is a permission to create, update and delete expenses
+	PermissionWrite Permission = "expenses:write"
+	// PermissionApprove is a permission to approve or reject expenses
+	PermissionApprove Permission = "expenses:approve"
+	// PermissionAdmin is a permission for admin only operations
+	PermissionAdmin Permission = "expenses:admin"
+)
+
+// Rule is a struct mapping a route pattern and method to the permission it requires
+type Rule struct {
+	Method     string
+	Path       string
+	Permission Permission
+}
+
+// Policy is a struct for the permissions of each role and the permission each route requires
+type Policy struct {
+	Roles map[string][]Permission
+	Rules []Rule
+}
+
+// DefaultPolicy is a policy of the expenses API
+var DefaultPolicy = Policy{
+	Roles: map[string][]Permission{
+		RoleViewer:   {PermissionRead},
+		RoleEditor:   {PermissionRead, PermissionWrite},
+		RoleApprover: {PermissionRead, PermissionApprove},
+		RoleAdmin:    {PermissionRead, PermissionWrite, PermissionApprove, PermissionAdmin},
+	},
+	Rules: []Rule{
+		{Method: "GET", Path: "/expenses", Permission: PermissionRead},
+		{Method: "GET", Path: "/expenses/export", Permission: PermissionRead},
+		{Method: "GET", Path: "/expenses/search", Permission: PermissionRead},
+		{Method: "GET", Path: "/expenses/:id", Permission: PermissionRead},
+		{Method: "POST", Path: "/expenses", Permission: PermissionWrite},
+		{Method: "POST", Path: "/expenses/import", Permission: PermissionWrite},
+		{Method: "POST", Path: "/expenses/batch", Permission: PermissionWrite},
+		{Method: "PUT", Path: "/expenses/:id", Permission: PermissionWrite},
+		{Method: "PATCH", Path: "/expenses/:id", Permission: PermissionWrite},
+		{Method: "DELETE", Path: "/expenses/:id", Permission: PermissionWrite},
+		{Method: "POST", Path: "/expenses/:id/restore", Permission: PermissionWrite},
+		{Method: "DELETE", Path: "/expenses/:id/purge", Permission: PermissionAdmin},
+		{Method: "POST", Path: "/expenses/:id/submit", Permission: PermissionWrite},
+		{Method: "POST", Path: "/expenses/:id/reopen", Permission: PermissionWrite},
+		{Method: "POST", Path: "/expenses/:id/approve", Permission: PermissionApprove},
+		{Method: "POST", Path: "/expenses/:id/reject", Permission: PermissionApprove},
+		{Method: "POST", Path: "/expenses/:id/reimburse", Permission: PermissionApprove},
+		{Method: "GET", Path: "/expenses/:id/transitions", Permission: PermissionRead},
+		{Method: "GET", Path: "/expenses/:id/history", Permission: PermissionRead},
+		{Method: "GET", Path: "/expenses/:id/attachments", Permission: PermissionRead},
+		{Method: "POST", Path: "/expenses/:id/attachments", Permission: PermissionWrite},
+		{Method: "GET", Path: "/expenses/:id/attachments/:attachment_id", Permission: PermissionRead},
+		{Method: "DELETE", Path: "/expenses/:id/attachments/:attachment_id", Permission: PermissionWrite},
+		{Method: "GET", Path: "/categories", Permission: PermissionRead},
+		{Method: "GET", Path: "/categories/:id", Permission: PermissionRead},
+		{Method: "POST", Path: "/categories", Permission: PermissionAdmin},
+		{Method: "PUT", Path: "/categories/:id", Permission: PermissionAdmin},
+		{Method: "DELETE", Path: "/categories/:id", Permission: PermissionAdmin},
+		{Method: "GET", Path: "/tags", Permission: PermissionRead},
+		{Method: "PUT", Path: "/tags/:name", Permission: PermissionWrite},
+		{Method: "POST", Path: "/tags/merge", Permission: PermissionWrite},
+		{Method: "GET", Path: "/reports", Permission: PermissionRead},
+		{Method: "GET", Path: "/recurring-expenses", Permission: PermissionRead},
+		{Method: "GET", Path: "/recurring-expenses/:id", Permission: PermissionRead},
+		{Method: "GET", Path: "/recurring-expenses/:id/preview", Permission: PermissionRead},
+		{Method: "POST", Path: "/recurring-expenses", Permission: PermissionWrite},
+		{Method: "PUT", Path: "/recurring-expenses/:id", Permission: PermissionWrite},
+		{Method: "DELETE", Path: "/recurring-expenses/:id", Permission: PermissionWrite},
+		{Method: "POST", Path: "/recurring-expenses/:id/pause", Permission: PermissionWrite},
+		{Method: "POST", Path: "/recurring-expenses/:id/resume", Permission: PermissionWrite},
+		{Method: "GET", Path: "/budgets", Permission: PermissionRead},
+		{Method: "GET", Path: "/budgets/:id", Permission: PermissionRead},
+		{Method: "GET", Path: "/budgets/:id/status", Permission: PermissionRead},
+		{Method: "GET", Path: "/budgets/:id/alerts", Permission: PermissionRead},
+		{Method: "POST", Path: "/budgets", Permission: PermissionWrite},
+		{Method: "PUT", Path: "/budgets/:id", Permission: PermissionWrite},
+		{Method: "DELETE", Path: "/budgets/:id", Permission: PermissionWrite},
+		{Method: "GET", Path: "/audit", Permission: PermissionAdmin},
+	},
+}
+
+// Required is a function to find the permission a route requires
+func (p Policy) Required(method string, path string) (Permission, bool) {
+	for _, rule := range p.Rules {
+		if strings.EqualFold(rule.Method, method) && rule.Path == path {
+			return rule.Permission, true
+		}
+	}
+	return "", false
+}
+
+// Grants is a function to check if any of roles holds a permission
+func (p Policy) Grants(roles []string, permission Permission) bool {
+	for _, role := range roles {
+		for _, granted := range p.Roles[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allows is a function to check if roles may call a route, routes without a rule are denied
+func (p Policy) Allows(roles []string, method string, path string) bool {
+	permission, ok := p.Required(method, path)
+	return ok && p.Grants(roles, permission)
+}
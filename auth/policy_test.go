@@ -0,0 +1,44 @@
+//go:build unit
+
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPolicyAllows(t *testing.T) {
+	subtests := []struct {
+		role     string
+		method   string
+		path     string
+		expected bool
+	}{
+		{role: RoleViewer, method: "GET", path: "/expenses", expected: true},
+		{role: RoleViewer, method: "GET", path: "/expenses/:id", expected: true},
+		{role: RoleViewer, method: "POST", path: "/expenses", expected: false},
+		{role: RoleViewer, method: "DELETE", path: "/expenses/:id", expected: false},
+		{role: RoleEditor, method: "PUT", path: "/expenses/:id", expected: true},
+		{role: RoleEditor, method: "PATCH", path: "/expenses/:id", expected: true},
+		{role: RoleEditor, method: "DELETE", path: "/expenses/:id/purge", expected: false},
+		{role: RoleApprover, method: "GET", path: "/expenses/:id", expected: true},
+		{role: RoleApprover, method: "PUT", path: "/expenses/:id", expected: false},
+		{role: RoleAdmin, method: "DELETE", path: "/expenses/:id/purge", expected: true},
+		{role: RoleAdmin, method: "GET", path: "/not-declared", expected: false},
+		{role: "stranger", method: "GET", path: "/expenses", expected: false},
+	}
+	for _, subtest := range subtests {
+		t.Run(subtest.role+" "+subtest.method+" "+subtest.path, func(t *testing.T) {
+			assert.Equal(t, subtest.expected, DefaultPolicy.Allows([]string{subtest.role}, subtest.method, subtest.path))
+		})
+	}
+}
+
+func TestPolicyWithSeveralRoles(t *testing.T) {
+	roles := []string{RoleViewer, RoleApprover}
+
+	assert.True(t, DefaultPolicy.Grants(roles, PermissionApprove))
+	assert.False(t, DefaultPolicy.Grants(roles, PermissionWrite))
+	assert.False(t, DefaultPolicy.Grants(nil, PermissionRead))
+}
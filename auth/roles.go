@@ -0,0 +1,12 @@
+package auth
+
+const (
+	// RoleViewer is a role that can read expenses
+	RoleViewer = "viewer"
+	// RoleEditor is a role that can read and write expenses
+	RoleEditor = "editor"
+	// RoleApprover is a role that can read and approve expenses
+	RoleApprover = "approver"
+	// RoleAdmin is a role that can do everything including admin only operations
+	RoleAdmin = "admin"
+)
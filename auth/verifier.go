@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"sync"
+
+	"github.com/walkmanrd/assessment/configs"
+)
+
+var (
+	defaultVerifierOnce sync.Once
+	defaultVerifier     *Verifier
+	defaultVerifierErr  error
+)
+
+// NewVerifierFromConfig is a function to create new verifier from JWT settings, nil when JWT is disabled
+func NewVerifierFromConfig(config configs.JWTConfig) (*Verifier, error) {
+	var load KeyLoader
+
+	switch {
+	case config.JWKSFile != "":
+		load = JWKSFileLoader(config.JWKSFile)
+	case config.KeysDir != "":
+		load = PEMDirLoader(config.KeysDir)
+	default:
+		return nil, nil
+	}
+
+	keys, err := NewKeySet(load)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVerifier(keys, config.Issuer, config.Audience), nil
+}
+
+// DefaultVerifier is a function to get the verifier built once from the environment, nil when JWT is disabled
+func DefaultVerifier() (*Verifier, error) {
+	defaultVerifierOnce.Do(func() {
+		defaultVerifier, defaultVerifierErr = NewVerifierFromConfig(configs.JWT())
+	})
+	return defaultVerifier, defaultVerifierErr
+}
@@ -0,0 +1,205 @@
+package configs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	// embedded zone database so APP_TIMEZONE works on images without tzdata
+	_ "time/tzdata"
+)
+
+// IsETagStrict is a function that check if updates must send an If-Match header
+func IsETagStrict() bool {
+	strict, _ := strconv.ParseBool(os.Getenv("ETAG_STRICT"))
+	return strict
+}
+
+// IsMigrateOnStart is a function that check if pending migrations are applied when the server starts
+func IsMigrateOnStart() bool {
+	migrate, err := strconv.ParseBool(os.Getenv("MIGRATE_ON_START"))
+	return err != nil || migrate
+}
+
+// DefaultCurrency is a function that return the currency of expenses sent without one
+func DefaultCurrency() string {
+	if currency := os.Getenv("DEFAULT_CURRENCY"); currency != "" {
+		return currency
+	}
+	return "THB"
+}
+
+// Location is a function that return the timezone expense dates default to today in,
+// APP_TIMEZONE falls back to Asia/Bangkok and an unknown zone to UTC
+func Location() *time.Location {
+	name := os.Getenv("APP_TIMEZONE")
+	if name == "" {
+		name = "Asia/Bangkok"
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// IdempotencyTTL is a function that return how long Idempotency-Key responses are kept for replay,
+// IDEMPOTENCY_TTL is a Go duration and falls back to 24 hours
+func IdempotencyTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
+// minIdempotencyLease is a shortest Idempotency-Key lease, the lease is renewed every third of it
+const minIdempotencyLease = 10 * time.Millisecond
+
+// IdempotencyLease is a function that return how long an Idempotency-Key stays claimed by a request in flight,
+// the lease is renewed while the request runs and a claim left behind by a crashed request is taken over once it ran out,
+// IDEMPOTENCY_LEASE is a Go duration, it falls back to a minute and is raised to 10ms when shorter
+func IdempotencyLease() time.Duration {
+	lease, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_LEASE"))
+	if err != nil || lease <= 0 {
+		return time.Minute
+	}
+	if lease < minIdempotencyLease {
+		return minIdempotencyLease
+	}
+	return lease
+}
+
+// RecurringExpenseInterval is a function that return how often due recurring expenses are materialized,
+// RECURRING_EXPENSE_INTERVAL is a Go duration, it falls back to a minute and 0 turns the worker off
+func RecurringExpenseInterval() time.Duration {
+	value := os.Getenv("RECURRING_EXPENSE_INTERVAL")
+	if value == "" {
+		return time.Minute
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return time.Minute
+	}
+	return interval
+}
+
+// TxMaxAttempts is a function that return how many times a transaction is run before a serialization failure
+// or a deadlock is reported, TX_MAX_ATTEMPTS falls back to 3
+func TxMaxAttempts() int {
+	attempts, err := strconv.Atoi(os.Getenv("TX_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		return 3
+	}
+	return attempts
+}
+
+// QueryTimeout is a function that return how long the queries of an operation such as read, write or export may run,
+// QUERY_TIMEOUT_<OPERATION> overrides QUERY_TIMEOUT for one operation, both are Go durations,
+// an invalid value is skipped, it falls back to 30 seconds and 0 lets queries run until the request ends,
+// an export streams for as long as its file takes so only QUERY_TIMEOUT_EXPORT bounds it
+func QueryTimeout(operation string) time.Duration {
+	names, fallback := []string{"QUERY_TIMEOUT_" + strings.ToUpper(operation), "QUERY_TIMEOUT"}, 30*time.Second
+	if operation == "export" {
+		names, fallback = names[:1], 0
+	}
+
+	for _, name := range names {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			continue
+		}
+		return timeout
+	}
+	return fallback
+}
+
+// StorageConfig is a struct for attachment storage settings
+type StorageConfig struct {
+	Driver      string
+	Dir         string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+}
+
+// Storage is a function that return attachment storage settings, STORAGE_DRIVER is filesystem or s3
+func Storage() StorageConfig {
+	config := StorageConfig{
+		Driver:      os.Getenv("STORAGE_DRIVER"),
+		Dir:         os.Getenv("STORAGE_DIR"),
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:    os.Getenv("S3_REGION"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+	}
+	config.S3PathStyle, _ = strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+
+	if config.Driver == "" {
+		config.Driver = "filesystem"
+	}
+	if config.Dir == "" {
+		config.Dir = "data/attachments"
+	}
+	if config.S3Region == "" {
+		config.S3Region = "us-east-1"
+	}
+	return config
+}
+
+// AttachmentMaxBytes is a function that return the largest attachment accepted, ATTACHMENT_MAX_BYTES falls back to 10 MiB
+func AttachmentMaxBytes() int64 {
+	size, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_BYTES"), 10, 64)
+	if err != nil || size <= 0 {
+		return 10 << 20
+	}
+	return size
+}
+
+// BudgetThresholds is a function that return the default percentages of a budget that raise an alert,
+// BUDGET_ALERT_THRESHOLDS is a comma separated list and falls back to 50,80,100
+func BudgetThresholds() []int64 {
+	thresholds := []int64{}
+	for _, value := range strings.Split(os.Getenv("BUDGET_ALERT_THRESHOLDS"), ",") {
+		threshold, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || threshold <= 0 {
+			continue
+		}
+		thresholds = append(thresholds, threshold)
+	}
+
+	if len(thresholds) == 0 {
+		return []int64{50, 80, 100}
+	}
+	return thresholds
+}
+
+// JWTConfig is a struct for JWT bearer authentication settings
+type JWTConfig struct {
+	JWKSFile string
+	KeysDir  string
+	Issuer   string
+	Audience string
+}
+
+// JWT is a function that return JWT settings, JWT is disabled when neither a JWKS file nor a key directory is set
+func JWT() JWTConfig {
+	return JWTConfig{
+		JWKSFile: os.Getenv("JWT_JWKS_FILE"),
+		KeysDir:  os.Getenv("JWT_KEYS_DIR"),
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+	}
+}
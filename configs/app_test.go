@@ -0,0 +1,61 @@
+//go:build unit
+
+package configs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	subtests := []struct {
+		name      string
+		env       map[string]string
+		operation string
+		expected  time.Duration
+	}{
+		{name: "default", operation: "read", expected: 30 * time.Second},
+		{name: "global", env: map[string]string{"QUERY_TIMEOUT": "5s"}, operation: "read", expected: 5 * time.Second},
+		{name: "operation overrides global", env: map[string]string{"QUERY_TIMEOUT": "5s", "QUERY_TIMEOUT_WRITE": "0"}, operation: "write", expected: 0},
+		{name: "invalid operation falls back to global", env: map[string]string{"QUERY_TIMEOUT": "5s", "QUERY_TIMEOUT_READ": "5 seconds"}, operation: "read", expected: 5 * time.Second},
+		{name: "negative operation falls back to global", env: map[string]string{"QUERY_TIMEOUT": "5s", "QUERY_TIMEOUT_WRITE": "-1s"}, operation: "write", expected: 5 * time.Second},
+		{name: "export is unbounded by default", env: map[string]string{"QUERY_TIMEOUT": "5s"}, operation: "export", expected: 0},
+		{name: "export with its own timeout", env: map[string]string{"QUERY_TIMEOUT_EXPORT": "10m"}, operation: "export", expected: 10 * time.Minute},
+	}
+
+	for _, subtest := range subtests {
+		t.Run(subtest.name, func(t *testing.T) {
+			for _, name := range []string{"QUERY_TIMEOUT", "QUERY_TIMEOUT_READ", "QUERY_TIMEOUT_WRITE", "QUERY_TIMEOUT_EXPORT"} {
+				t.Setenv(name, subtest.env[name])
+			}
+
+			if timeout := QueryTimeout(subtest.operation); timeout != subtest.expected {
+				t.Errorf("expected %s, got %s", subtest.expected, timeout)
+			}
+		})
+	}
+}
+
+func TestIdempotencyLease(t *testing.T) {
+	subtests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "default", expected: time.Minute},
+		{name: "set", value: "30s", expected: 30 * time.Second},
+		{name: "invalid falls back to default", value: "a minute", expected: time.Minute},
+		{name: "zero falls back to default", value: "0", expected: time.Minute},
+		{name: "too short is raised to minimum", value: "2ns", expected: 10 * time.Millisecond},
+	}
+
+	for _, subtest := range subtests {
+		t.Run(subtest.name, func(t *testing.T) {
+			t.Setenv("IDEMPOTENCY_LEASE", subtest.value)
+
+			if lease := IdempotencyLease(); lease != subtest.expected {
+				t.Errorf("expected %s, got %s", subtest.expected, lease)
+			}
+		})
+	}
+}
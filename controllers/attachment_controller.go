@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// multipartOverhead is a number of bytes allowed in an upload besides the attachment for its multipart headers and boundaries
+const multipartOverhead = 64 << 10
+
+// AttachmentController is a struct for expense attachment controller
+type AttachmentController struct {
+	attachmentService services.AttachmentService
+}
+
+// NewAttachmentController is a function to create new attachment controller whose requests share the connection pool of db
+func NewAttachmentController(db *sql.DB) *AttachmentController {
+	return &AttachmentController{
+		attachmentService: *services.NewAttachmentService(*repositories.NewAttachmentRepository(db), *repositories.NewExpenseRepository(db), nil),
+	}
+}
+
+// attachmentParams is a function to get and check the expense id and optionally the attachment id of a request
+func attachmentParams(e echo.Context, withAttachment bool) (string, string, error) {
+	expenseID := e.Param("id")
+	if _, err := strconv.ParseInt(expenseID, 10, 64); err != nil {
+		return "", "", fmt.Errorf("invalid parameter id")
+	}
+
+	if !withAttachment {
+		return expenseID, "", nil
+	}
+
+	id := e.Param("attachment_id")
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return "", "", fmt.Errorf("invalid parameter attachment_id")
+	}
+
+	return expenseID, id, nil
+}
+
+// GET /expenses/:id/attachments
+// Index is a function to get all attachments of an expense
+func (c *AttachmentController) Index(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	expenseID, _, err := attachmentParams(e, false)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	attachments, status, err := c.attachmentService.Gets(e.Request().Context(), ownerID, expenseID)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, attachments)
+}
+
+// POST /expenses/:id/attachments
+// Store is a function to attach a receipt uploaded as the multipart file field to an expense
+func (c *AttachmentController) Store(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	expenseID, _, err := attachmentParams(e, false)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	// the body is cut off past the largest attachment so an oversized upload isn't spooled in full
+	maxBytes := configs.AttachmentMaxBytes()
+	e.Request().Body = http.MaxBytesReader(e.Response(), e.Request().Body, maxBytes+multipartOverhead)
+
+	header, err := e.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && header.Size > maxBytes) {
+		return e.JSON(http.StatusRequestEntityTooLarge, types.Error{Message: fmt.Sprintf("attachment is larger than %d bytes", maxBytes)})
+	}
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+	defer file.Close()
+
+	attachment, created, status, err := c.attachmentService.Create(e.Request().Context(), ownerID, expenseID, header.Filename, file)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	if !created {
+		return e.JSON(http.StatusOK, attachment)
+	}
+
+	return e.JSON(http.StatusCreated, attachment)
+}
+
+// GET /expenses/:id/attachments/:attachment_id
+// Show is a function to download an attachment of an expense
+func (c *AttachmentController) Show(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	expenseID, id, err := attachmentParams(e, true)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	attachment, content, status, err := c.attachmentService.Open(e.Request().Context(), ownerID, expenseID, id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+	defer content.Close()
+
+	e.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", attachment.Filename))
+	e.Response().Header().Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+	e.Response().Header().Set(headerETag, strconv.Quote(attachment.Checksum))
+
+	return e.Stream(http.StatusOK, attachment.ContentType, content)
+}
+
+// DELETE /expenses/:id/attachments/:attachment_id
+// Delete is a function to delete an attachment of an expense
+func (c *AttachmentController) Delete(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	expenseID, id, err := attachmentParams(e, true)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	status, err := c.attachmentService.DeleteById(e.Request().Context(), ownerID, expenseID, id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,366 @@
+//go:build unit
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/storage"
+)
+
+// pngContent is the content of a 1x1 PNG receipt
+var pngContent = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89")
+
+// pngChecksum is the sha256 checksum of pngContent
+var pngChecksum = fmt.Sprintf("%x", sha256.Sum256(pngContent))
+
+var attachmentColumns = []string{"id", "expense_id", "filename", "content_type", "size", "checksum", "created_at"}
+
+func setupAttachmentTest(t *testing.T, db *sql.DB) (*AttachmentController, storage.Storage) {
+	store := storage.NewFileSystem(t.TempDir())
+	attachmentService := services.NewAttachmentService(*repositories.NewAttachmentRepository(db), *repositories.NewExpenseRepository(db), store)
+
+	return &AttachmentController{attachmentService: *attachmentService}, store
+}
+
+func attachmentContext(method string, target string, body io.Reader, contentType string, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames([]string{"id", "attachment_id"}[:len(params)]...)
+	c.SetParamValues(params...)
+	auth.SetUser(c, testUser)
+	return c, rec
+}
+
+func uploadContext(t *testing.T, filename string, content []byte) (echo.Context, *httptest.ResponseRecorder) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	return attachmentContext(http.MethodPost, "/expenses/1/attachments", body, writer.FormDataContentType(), "1")
+}
+
+func expectExpense(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(regexp.QuoteMeta(`FROM expenses WHERE id = $1 AND owner_id = $2`)).
+		ExpectQuery().
+		WithArgs("1", "7").
+		WillReturnRows(sqlmock.NewRows(importColumns).AddRow("1", "ramen", 120, "THB", "lunch", `{"food"}`, nil, "2022-01-02", testTime, testTime, "draft", 1))
+}
+
+func expectChecksumLock(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtext($1))`)).
+		WithArgs(pngChecksum).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestStoreAttachment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	attachmentController, store := setupAttachmentTest(t, db)
+	checksum := ""
+
+	t.Run("new content", func(t *testing.T) {
+		c, rec := uploadContext(t, `C:\receipts\ramen.png`, pngContent)
+		expectExpense(mock)
+		expectChecksumLock(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO attachments`)).
+			WithArgs("1", "ramen.png", "image/png", int64(len(pngContent)), pngChecksum).
+			WillReturnRows(sqlmock.NewRows(attachmentColumns).AddRow("2", "1", "ramen.png", "image/png", len(pngContent), pngChecksum, testTime))
+		mock.ExpectCommit()
+
+		if assert.NoError(t, attachmentController.Store(c)) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			var attachment models.Attachment
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &attachment))
+			assert.Equal(t, "ramen.png", attachment.Filename)
+			checksum = attachment.Checksum
+		}
+
+		content, err := store.Get("sha256/" + checksum[:2] + "/" + checksum)
+		if assert.NoError(t, err) {
+			stored, _ := io.ReadAll(content)
+			content.Close()
+			assert.Equal(t, pngContent, stored)
+		}
+	})
+
+	t.Run("duplicate content", func(t *testing.T) {
+		c, rec := uploadContext(t, "again.png", pngContent)
+		expectExpense(mock)
+		expectChecksumLock(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO attachments`)).
+			WillReturnRows(sqlmock.NewRows(attachmentColumns))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE expense_id = $1 AND checksum = $2`)).
+			WillReturnRows(sqlmock.NewRows(attachmentColumns).AddRow("2", "1", "ramen.png", "image/png", len(pngContent), pngChecksum, testTime))
+		mock.ExpectCommit()
+
+		if assert.NoError(t, attachmentController.Store(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"id":"2"`)
+		}
+	})
+
+	t.Run("unsupported content", func(t *testing.T) {
+		c, rec := uploadContext(t, "receipt.png", []byte("just some text"))
+		expectExpense(mock)
+
+		if assert.NoError(t, attachmentController.Store(c)) {
+			assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+			assert.Equal(t, `{"message":"unsupported attachment type text/plain"}`, strings.TrimSpace(rec.Body.String()))
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		t.Setenv("ATTACHMENT_MAX_BYTES", "8")
+		c, rec := uploadContext(t, "ramen.png", pngContent)
+
+		if assert.NoError(t, attachmentController.Store(c)) {
+			assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		}
+	})
+
+	t.Run("body beyond limit", func(t *testing.T) {
+		t.Setenv("ATTACHMENT_MAX_BYTES", "8")
+		c, rec := uploadContext(t, "ramen.png", bytes.Repeat(pngContent, 10000))
+
+		if assert.NoError(t, attachmentController.Store(c)) {
+			assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+			assert.Equal(t, `{"message":"attachment is larger than 8 bytes"}`, strings.TrimSpace(rec.Body.String()))
+		}
+	})
+
+	t.Run("expense not found", func(t *testing.T) {
+		c, rec := uploadContext(t, "ramen.png", pngContent)
+		mock.ExpectPrepare(regexp.QuoteMeta(`FROM expenses WHERE id = $1 AND owner_id = $2`)).
+			ExpectQuery().
+			WillReturnError(sql.ErrNoRows)
+
+		if assert.NoError(t, attachmentController.Store(c)) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShowAndDeleteAttachment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	attachmentController, store := setupAttachmentTest(t, db)
+	key := "sha256/" + pngChecksum[:2] + "/" + pngChecksum
+	assert.NoError(t, store.Put(key, bytes.NewReader(pngContent), int64(len(pngContent)), "image/png"))
+
+	attachmentRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows(attachmentColumns).AddRow("2", "1", "ramen.png", "image/png", len(pngContent), pngChecksum, testTime)
+	}
+
+	t.Run("download", func(t *testing.T) {
+		c, rec := attachmentContext(http.MethodGet, "/expenses/1/attachments/2", nil, "", "1", "2")
+		expectExpense(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE id = $1 AND expense_id = $2`)).
+			WithArgs("2", "1").
+			WillReturnRows(attachmentRow())
+
+		if assert.NoError(t, attachmentController.Show(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "image/png", rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, `inline; filename="ramen.png"`, rec.Header().Get(echo.HeaderContentDisposition))
+			assert.Equal(t, pngContent, rec.Body.Bytes())
+		}
+	})
+
+	t.Run("delete keeps shared content", func(t *testing.T) {
+		c, rec := attachmentContext(http.MethodDelete, "/expenses/1/attachments/2", nil, "", "1", "2")
+		expectExpense(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE id = $1 AND expense_id = $2`)).
+			WillReturnRows(attachmentRow())
+		expectChecksumLock(mock)
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM attachments WHERE id = $1 AND expense_id = $2`)).
+			WithArgs("2", "1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS`)).
+			WithArgs(pngChecksum).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectCommit()
+
+		if assert.NoError(t, attachmentController.Delete(c)) {
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+		}
+		_, err := store.Get(key)
+		assert.NoError(t, err)
+	})
+
+	t.Run("delete removes unused content", func(t *testing.T) {
+		c, rec := attachmentContext(http.MethodDelete, "/expenses/1/attachments/2", nil, "", "1", "2")
+		expectExpense(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE id = $1 AND expense_id = $2`)).
+			WillReturnRows(attachmentRow())
+		expectChecksumLock(mock)
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM attachments`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS`)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectCommit()
+
+		if assert.NoError(t, attachmentController.Delete(c)) {
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+		}
+		_, err := store.Get(key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("delete rolls back when already deleted", func(t *testing.T) {
+		c, rec := attachmentContext(http.MethodDelete, "/expenses/1/attachments/2", nil, "", "1", "2")
+		expectExpense(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE id = $1 AND expense_id = $2`)).
+			WillReturnRows(attachmentRow())
+		expectChecksumLock(mock)
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM attachments`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		if assert.NoError(t, attachmentController.Delete(c)) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		c, rec := attachmentContext(http.MethodGet, "/expenses/1/attachments/9", nil, "", "1", "9")
+		expectExpense(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE id = $1 AND expense_id = $2`)).
+			WillReturnError(sql.ErrNoRows)
+
+		if assert.NoError(t, attachmentController.Show(c)) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+			assert.Equal(t, `{"message":"attachment not found"}`, strings.TrimSpace(rec.Body.String()))
+		}
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttachmentsOfSubmittedExpense(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	attachmentController, _ := setupAttachmentTest(t, db)
+
+	expectSubmittedExpense := func() {
+		mock.ExpectPrepare(regexp.QuoteMeta(`FROM expenses WHERE id = $1 AND owner_id = $2`)).
+			ExpectQuery().
+			WithArgs("1", "7").
+			WillReturnRows(sqlmock.NewRows(importColumns).AddRow("1", "ramen", 120, "THB", "lunch", `{"food"}`, nil, "2022-01-02", testTime, testTime, "submitted", 2))
+	}
+
+	t.Run("store", func(t *testing.T) {
+		c, rec := uploadContext(t, "ramen.png", pngContent)
+		expectSubmittedExpense()
+
+		if assert.NoError(t, attachmentController.Store(c)) {
+			assert.Equal(t, http.StatusConflict, rec.Code)
+			assert.Equal(t, `{"message":"expense is submitted and can no longer be edited"}`, strings.TrimSpace(rec.Body.String()))
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		c, rec := attachmentContext(http.MethodDelete, "/expenses/1/attachments/2", nil, "", "1", "2")
+		expectSubmittedExpense()
+
+		if assert.NoError(t, attachmentController.Delete(c)) {
+			assert.Equal(t, http.StatusConflict, rec.Code)
+			assert.Equal(t, `{"message":"expense is submitted and can no longer be edited"}`, strings.TrimSpace(rec.Body.String()))
+		}
+	})
+
+	t.Run("index", func(t *testing.T) {
+		c, rec := attachmentContext(http.MethodGet, "/expenses/1/attachments", nil, "", "1")
+		expectSubmittedExpense()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE expense_id = $1 ORDER BY id ASC`)).
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows(attachmentColumns).AddRow("2", "1", "ramen.png", "image/png", len(pngContent), pngChecksum, testTime))
+
+		if assert.NoError(t, attachmentController.Index(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeExpenseRemovesAttachmentContent(t *testing.T) {
+	admin := models.User{ID: "9", Name: "admin", Roles: []string{auth.RoleAdmin}}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	store := storage.NewFileSystem(t.TempDir())
+	expenseService := services.NewExpenseService(*repositories.NewExpenseRepository(db), *repositories.NewCategoryRepository(db), *repositories.NewAttachmentRepository(db), store)
+	expenseController := &ExpenseController{expenseService: *expenseService}
+
+	key := "sha256/" + pngChecksum[:2] + "/" + pngChecksum
+	assert.NoError(t, store.Put(key, bytes.NewReader(pngContent), int64(len(pngContent)), "image/png"))
+
+	c, rec := attachmentContext(http.MethodDelete, "/expenses/1/purge", nil, "", "1")
+	auth.SetUser(c, admin)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE expense_id = $1`)).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows(attachmentColumns).AddRow("2", "1", "ramen.png", "image/png", len(pngContent), pngChecksum, testTime))
+	expectActor(mock, admin)
+	mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM expenses WHERE id = $1`)).
+		ExpectExec().
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectChecksumLock(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS`)).
+		WithArgs(pngChecksum).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectCommit()
+
+	if assert.NoError(t, expenseController.Purge(c)) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+	_, err = store.Get(key)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// AuditController is a struct for audit controller
+type AuditController struct {
+	auditService services.AuditService
+}
+
+// NewAuditController is a function to create new audit controller whose requests share the connection pool of db
+func NewAuditController(db *sql.DB) *AuditController {
+	return &AuditController{
+		auditService: *services.NewAuditService(*repositories.NewAuditRepository(db)),
+	}
+}
+
+// GET /audit
+// Index is a function to get the audit events of all expenses matching the filters newest first
+func (c *AuditController) Index(e echo.Context) error {
+	var query types.AuditQuery
+
+	if err := bindAndValidateRequest(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	events, status, err := c.auditService.Gets(e.Request().Context(), query)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, events)
+}
+
+// GET /expenses/:id/history
+// History is a function to get the audit events of an expense newest first
+func (c *AuditController) History(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	var query types.AuditQuery
+
+	if err := bindAndValidateRequest(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	events, status, err := c.auditService.History(e.Request().Context(), ownerID, id, query)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, events)
+}
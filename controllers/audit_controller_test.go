@@ -0,0 +1,134 @@
+//go:build unit
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+var (
+	findAuditSQL  = regexp.QuoteMeta(`FROM audit_events a LEFT JOIN users u ON u.id = a.actor_id`)
+	countAuditSQL = regexp.QuoteMeta(`SELECT COUNT(*) FROM audit_events a WHERE`)
+	auditColumns  = []string{"id", "expense_id", "owner_id", "actor_id", "name", "request_id", "action", "changes", "created_at"}
+)
+
+func setupAuditTest(t *testing.T) (*AuditController, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return &AuditController{auditService: *services.NewAuditService(*repositories.NewAuditRepository(db))}, mock
+}
+
+func TestGetAuditEvents(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/audit?actor_id=7&action=update&from=2022-01-01&limit=10", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	auditController, mock := setupAuditTest(t)
+	mock.ExpectQuery(findAuditSQL+`.*WHERE TRUE AND a\.actor_id = \$1 AND a\.action = \$2 AND a\.created_at >= \$3::date.*LIMIT \$4 OFFSET \$5`).
+		WithArgs("7", "update", "2022-01-01", 10, 0).
+		WillReturnRows(sqlmock.NewRows(auditColumns).
+			AddRow("12", "1", "7", "7", "tester", "req-1", "update", []byte(`{"amount": {"to": 90, "from": 79}}`), testTime))
+	mock.ExpectQuery(countAuditSQL).
+		WithArgs("7", "update", "2022-01-01").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	if assert.NoError(t, auditController.Index(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"data":[{"id":"12","expense_id":"1","owner_id":"7","actor_id":"7","actor_name":"tester","request_id":"req-1","action":"update","changes":{"amount":{"to":90,"from":79}},"created_at":"2022-01-02T03:04:05Z"}],"total":1}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAuditEventsInvalidAction(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/audit?action=rename", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	auditController, _ := setupAuditTest(t)
+
+	if assert.NoError(t, auditController.Index(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestGetExpenseHistory(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id/history")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	auditController, mock := setupAuditTest(t)
+	mock.ExpectQuery(findAuditSQL+`.*WHERE TRUE AND a\.expense_id = \$1 AND a\.owner_id = \$2`).
+		WithArgs("1", "7", 50, 0).
+		WillReturnRows(sqlmock.NewRows(auditColumns).
+			AddRow("13", "1", "7", "7", "tester", nil, "delete", []byte(`{"deleted_at": {"to": "2022-01-02T03:04:05+00:00", "from": null}}`), testTime).
+			AddRow("12", "1", "7", nil, nil, nil, "create", []byte(`{"title": {"to": "taxi", "from": null}}`), testTime))
+	mock.ExpectQuery(countAuditSQL).
+		WithArgs("1", "7").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	if assert.NoError(t, auditController.History(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"action":"delete"`)
+		assert.Contains(t, rec.Body.String(), `"actor_id":null,"actor_name":null,"request_id":null,"action":"create"`)
+		assert.Contains(t, rec.Body.String(), `"total":2`)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteExpenseRecordsRequestID(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-42")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT set_config('app.actor_id', $1, true), set_config('app.request_id', $2, true)`)).
+		WithArgs("7", "req-42").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET deleted_at = NOW()`)).
+		ExpectExec().
+		WithArgs("1", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Delete(c)) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// BudgetController is a struct for budget controller
+type BudgetController struct {
+	budgetService services.BudgetService
+}
+
+// NewBudgetController is a function to create new budget controller whose requests share the connection pool of db
+func NewBudgetController(db *sql.DB) *BudgetController {
+	return &BudgetController{
+		budgetService: *services.NewBudgetService(*repositories.NewBudgetRepository(db), *repositories.NewCategoryRepository(db)),
+	}
+}
+
+// GET /budgets
+// Index is a function to get all budgets
+func (c *BudgetController) Index(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	budgets, status, err := c.budgetService.Gets(e.Request().Context(), ownerID)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, budgets)
+}
+
+// GET /budgets/:id
+// Show is a function to get a budget by id
+func (c *BudgetController) Show(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	budget, status, err := c.budgetService.GetById(e.Request().Context(), ownerID, id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, budget)
+}
+
+// POST /budgets
+// Store is a function to create a new budget
+func (c *BudgetController) Store(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	var budgetRequest types.BudgetRequest
+
+	if err := bindAndValidateRequest(e, &budgetRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	budget, status, err := c.budgetService.Create(e.Request().Context(), ownerID, budgetRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusCreated, budget)
+}
+
+// PUT /budgets/:id
+// Update is a function to update a budget by id
+func (c *BudgetController) Update(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	var budgetRequest types.BudgetRequest
+
+	if err := bindAndValidateRequest(e, &budgetRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	budget, status, err := c.budgetService.UpdateById(e.Request().Context(), ownerID, id, budgetRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, budget)
+}
+
+// DELETE /budgets/:id
+// Delete is a function to delete a budget by id
+func (c *BudgetController) Delete(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	if status, err := c.budgetService.DeleteById(e.Request().Context(), ownerID, id); err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// GET /budgets/:id/status
+// Status is a function to get how much of a budget is spent in the period containing a date, today by default
+func (c *BudgetController) Status(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	var query types.BudgetStatusQuery
+
+	if err := bindAndValidateRequest(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	budgetStatus, status, err := c.budgetService.Status(e.Request().Context(), ownerID, id, query.Date)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, budgetStatus)
+}
+
+// GET /budgets/:id/alerts
+// Alerts is a function to get the threshold alerts raised by a budget
+func (c *BudgetController) Alerts(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	alerts, status, err := c.budgetService.Alerts(e.Request().Context(), ownerID, id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, alerts)
+}
@@ -0,0 +1,106 @@
+//go:build unit
+
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+var budgetColumns = []string{"id", "name", "amount", "currency", "period", "tag", "category_id", "thresholds", "created_at", "updated_at"}
+
+func setupBudgetTest(db *sql.DB) *BudgetController {
+	budgetService := services.NewBudgetService(*repositories.NewBudgetRepository(db), *repositories.NewCategoryRepository(db))
+
+	return &BudgetController{budgetService: *budgetService}
+}
+
+func budgetContext(method string, target string, body string, id string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if id != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+	}
+	auth.SetUser(c, testUser)
+	return c, rec
+}
+
+func TestCreateBudget(t *testing.T) {
+	c, rec := budgetContext(http.MethodPost, "/budgets", `{"name":"eating out","amount":1000,"period":"monthly","tag":" Food ","thresholds":[100,50,80,80]}`, "")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO budgets`)).
+		WithArgs("7", "eating out", "1000", "THB", "monthly", "food", nil, "{50,80,100}").
+		WillReturnRows(sqlmock.NewRows(budgetColumns).AddRow("3", "eating out", 1000, "THB", "monthly", "food", nil, "{50,80,100}", testTime, testTime))
+
+	if assert.NoError(t, setupBudgetTest(db).Store(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"id":"3","name":"eating out","amount":1000,"currency":"THB","period":"monthly","tag":"food","thresholds":[50,80,100],`+
+			`"created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateBudgetInvalid(t *testing.T) {
+	subtests := map[string]string{
+		"no scope":        `{"name":"eating out","amount":1000,"period":"monthly"}`,
+		"two scopes":      `{"name":"eating out","amount":1000,"period":"monthly","tag":"food","category_id":"1"}`,
+		"negative amount": `{"name":"eating out","amount":-1,"period":"monthly","tag":"food"}`,
+		"unknown period":  `{"name":"eating out","amount":1000,"period":"daily","tag":"food"}`,
+		"zero threshold":  `{"name":"eating out","amount":1000,"period":"monthly","tag":"food","thresholds":[0]}`,
+		"blank tag":       `{"name":"eating out","amount":1000,"period":"monthly","tag":"  "}`,
+	}
+	for name, body := range subtests {
+		t.Run(name, func(t *testing.T) {
+			c, rec := budgetContext(http.MethodPost, "/budgets", body, "")
+
+			if assert.NoError(t, setupBudgetTest(nil).Store(c)) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			}
+		})
+	}
+}
+
+func TestBudgetStatus(t *testing.T) {
+	c, rec := budgetContext(http.MethodGet, "/budgets/3/status?date=2022-05-18", "", "3")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM budgets WHERE id = $1 AND owner_id = $2`)).
+		WithArgs("3", "7").
+		WillReturnRows(sqlmock.NewRows(budgetColumns).AddRow("3", "groceries", 3000, "THB", "quarterly", nil, "2", "{50,80,100}", testTime, testTime))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(amount), 0), COUNT(*) FROM expenses WHERE owner_id = $1 AND deleted_at IS NULL AND currency = $2 AND spent_at >= $3 AND spent_at <= $4 AND category_id IN`)).
+		WithArgs("7", "THB", "2022-04-01", "2022-06-30", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"sum", "count"}).AddRow("2500", 12))
+
+	if assert.NoError(t, setupBudgetTest(db).Status(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"budget_id":"3","period_start":"2022-04-01","period_end":"2022-06-30","amount":3000,"spent":2500,"remaining":500,"percent":83.33,"count":12,"reached":[50,80]}`,
+			strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// CategoryController is a struct for category controller
+type CategoryController struct {
+	categoryService services.CategoryService
+}
+
+// NewCategoryController is a function to create new category controller whose requests share the connection pool of db
+func NewCategoryController(db *sql.DB) *CategoryController {
+	return &CategoryController{
+		categoryService: *services.NewCategoryService(*repositories.NewCategoryRepository(db)),
+	}
+}
+
+// GET /categories
+// Index is a function to get all categories
+func (c *CategoryController) Index(e echo.Context) error {
+	categories, err := c.categoryService.Gets(e.Request().Context())
+
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, categories)
+}
+
+// GET /categories/:id
+// Show is a function to get a category by id
+func (c *CategoryController) Show(e echo.Context) error {
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	category, status, err := c.categoryService.GetById(e.Request().Context(), id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, category)
+}
+
+// POST /categories
+// Store is a function to create a new category
+func (c *CategoryController) Store(e echo.Context) error {
+	var categoryRequest types.CategoryRequest
+
+	if err := bindAndValidateRequest(e, &categoryRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	category, status, err := c.categoryService.Create(e.Request().Context(), categoryRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusCreated, category)
+}
+
+// PUT /categories/:id
+// Update is a function to update a category by id
+func (c *CategoryController) Update(e echo.Context) error {
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	var categoryRequest types.CategoryRequest
+
+	if err := bindAndValidateRequest(e, &categoryRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	category, status, err := c.categoryService.UpdateById(e.Request().Context(), id, categoryRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, category)
+}
+
+// DELETE /categories/:id
+// Delete is a function to delete a category by id
+func (c *CategoryController) Delete(e echo.Context) error {
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	// the expenses left without the category are recorded as changed by the user deleting it
+	actor, _ := currentActor(e)
+
+	if status, err := c.categoryService.WithActor(actor).DeleteById(e.Request().Context(), id); err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
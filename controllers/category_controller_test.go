@@ -0,0 +1,257 @@
+//go:build unit
+
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+func setupCategoryTest(db *sql.DB) *CategoryController {
+	categoryRepository := repositories.NewCategoryRepository(db)
+	categoryService := services.NewCategoryService(*categoryRepository)
+
+	return &CategoryController{
+		categoryService: *categoryService,
+	}
+}
+
+func TestCreateCategory(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	body := `{"name":"Coffee","parent_id":"2"}`
+	req := httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, parent_id FROM categories WHERE id = $1`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow("2", "Beverage", nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO categories (name, parent_id) VALUES ($1, $2) RETURNING id, name, parent_id`)).
+		WithArgs("Coffee", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow("12", "Coffee", "2"))
+
+	categoryController := setupCategoryTest(db)
+
+	if assert.NoError(t, categoryController.Store(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"id":"12","name":"Coffee","parent_id":"2"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateCategoryBelowItself(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPut, "/categories/2", strings.NewReader(`{"name":"Beverage","parent_id":"12"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/categories/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, parent_id FROM categories WHERE id = $1`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow("2", "Beverage", nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, parent_id FROM categories WHERE id = $1`)).
+		WithArgs("12").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow("12", "Coffee", "2"))
+	mock.ExpectQuery(`WITH RECURSIVE descendants`).
+		WithArgs("2", "12").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	categoryController := setupCategoryTest(db)
+
+	if assert.NoError(t, categoryController.Update(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"message":"category cannot be moved below itself"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCategoryWithSubcategories(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/categories/2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/categories/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1)`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	categoryController := setupCategoryTest(db)
+
+	if assert.NoError(t, categoryController.Delete(c)) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	}
+}
+
+func TestDeleteCategoryBumpsExpenseVersions(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/categories/2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/categories/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1)`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	expectActor(mock, testUser)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM categories WHERE id = $1 FOR UPDATE`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE expenses SET category_id = NULL, updated_at = NOW(), version = version + 1 WHERE category_id = $1 AND status = 'draft'`)).
+		WithArgs("2").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM expenses WHERE category_id = $1 AND status <> 'draft')`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM categories WHERE id = $1`)).
+		WithArgs("2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	categoryController := setupCategoryTest(db)
+
+	if assert.NoError(t, categoryController.Delete(c)) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCategoryOfLockedExpenses(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/categories/2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/categories/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1)`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM categories WHERE id = $1 FOR UPDATE`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE expenses SET category_id = NULL, updated_at = NOW(), version = version + 1 WHERE category_id = $1 AND status = 'draft'`)).
+		WithArgs("2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM expenses WHERE category_id = $1 AND status <> 'draft')`)).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	categoryController := setupCategoryTest(db)
+
+	if assert.NoError(t, categoryController.Delete(c)) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, `{"message":"category is used by expenses locked by the approval workflow"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCategoryNotFound(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/categories/9", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/categories/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("9")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1)`)).
+		WithArgs("9").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM categories WHERE id = $1 FOR UPDATE`)).
+		WithArgs("9").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	categoryController := setupCategoryTest(db)
+
+	if assert.NoError(t, categoryController.Delete(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateExpenseWithUnknownCategory(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	body := `{"title":"strawberry smoothie","amount":79,"note":"night market","tags":["food"],"category_id":"99"}`
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, parent_id FROM categories WHERE id = $1`)).
+		WithArgs("99").
+		WillReturnError(sql.ErrNoRows)
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Store(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"message":"category not found"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
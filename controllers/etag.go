@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+)
+
+// headerETag is a response header carrying an entity tag
+const headerETag = "ETag"
+
+// expenseETag is a function to build an entity tag from an expense version
+func expenseETag(expense models.Expense) string {
+	return fmt.Sprintf(`"%d"`, expense.Version)
+}
+
+// ifMatchVersions is a function to read versions accepted by the If-Match header,
+// nil versions means the update is unconditional
+func ifMatchVersions(e echo.Context) ([]int, int, error) {
+	header := e.Request().Header.Get("If-Match")
+
+	if header == "" {
+		if configs.IsETagStrict() {
+			return nil, http.StatusPreconditionRequired, errors.New("If-Match header is required")
+		}
+		return nil, 0, nil
+	}
+
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, 0, nil
+		}
+
+		// If-Match uses strong comparison so weak tags never match
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+
+		if version, err := strconv.Atoi(strings.Trim(tag, `"`)); err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, 0, nil
+}
+
+// ifNoneMatch is a function to check if the If-None-Match header matches an entity tag
+func ifNoneMatch(e echo.Context, etag string) bool {
+	header := e.Request().Header.Get("If-None-Match")
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || (tag != "" && tag == etag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsVersion is a function to check if a version is in a list of versions
+func containsVersion(versions []int, version int) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+//go:build unit
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/types"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+const batchDeleteSQL = `UPDATE expenses SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft'`
+
+func batchContext(body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPost, "/expenses/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	return c, rec
+}
+
+func TestBatchAtomic(t *testing.T) {
+	c, rec := batchContext(`{"operations":[
+		{"op":"create","expense":{"title":"ramen","amount":120,"note":"lunch","tags":["food"],"spent_at":"2022-01-02"}},
+		{"op":"delete","id":"3"}
+	]}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO expenses`)).
+		WithArgs("7", "ramen", "120", "THB", "lunch", `{"food"}`, nil, "2022-01-02").
+		WillReturnRows(sqlmock.NewRows(importColumns).AddRow("5", "ramen", 120, "THB", "lunch", `{"food"}`, nil, "2022-01-02", testTime, testTime, "draft", 1))
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Batch(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response types.ExpenseBatchResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "atomic", response.Mode)
+		assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+		assert.Equal(t, "5", response.Results[0].Expense.ID)
+		assert.Equal(t, http.StatusNoContent, response.Results[1].Status)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchAtomicRetriesSerializationFailure(t *testing.T) {
+	c, rec := batchContext(`{"operations":[{"op":"delete","id":"3"},{"op":"delete","id":"4"}]}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("4", "7").
+		WillReturnError(&pq.Error{Code: "40P01", Message: "deadlock detected"})
+	mock.ExpectRollback()
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("4", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40001", Message: "could not serialize access"})
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("4", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Batch(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response types.ExpenseBatchResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, http.StatusNoContent, response.Results[0].Status)
+		assert.Equal(t, http.StatusNoContent, response.Results[1].Status)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchAtomicRetriesFailedStatement(t *testing.T) {
+	c, rec := batchContext(`{"operations":[{"op":"delete","id":"3"}]}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnError(&pq.Error{Code: "40001", Message: "could not serialize access"})
+	mock.ExpectRollback()
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Batch(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status":204`)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchAtomicStopsRetryingWhenRequestEnds(t *testing.T) {
+	t.Setenv("TX_MAX_ATTEMPTS", "5")
+	c, rec := batchContext(`{"operations":[{"op":"delete","id":"3"}]}`)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Millisecond)
+	defer cancel()
+	c.SetRequest(c.Request().WithContext(ctx))
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnError(&pq.Error{Code: "40001", Message: "could not serialize access"})
+	mock.ExpectRollback()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Batch(c)) {
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchAtomicGivesUpAfterMaxAttempts(t *testing.T) {
+	t.Setenv("TX_MAX_ATTEMPTS", "1")
+	c, rec := batchContext(`{"operations":[{"op":"delete","id":"3"}]}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40001", Message: "could not serialize access"})
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Batch(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "could not serialize access")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchAtomicRollsBack(t *testing.T) {
+	c, rec := batchContext(`{"mode":"atomic","operations":[
+		{"op":"delete","id":"3"},
+		{"op":"delete","id":"9"},
+		{"op":"delete","id":"4"}
+	]}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("9", "7").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(findExpenseSQL)).
+		ExpectQuery().
+		WithArgs("9", "7").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Batch(c)) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, `{"mode":"atomic","results":[{"index":0,"status":424,"error":"rolled back"},{"index":1,"status":404,"error":"expense not found"},{"index":2,"status":424,"error":"not applied"}]}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchBestEffort(t *testing.T) {
+	c, rec := batchContext(`{"mode":"best_effort","operations":[
+		{"op":"delete","id":"9"},
+		{"op":"update","id":"1","expense":{"title":"ramen"}},
+		{"op":"archive","id":"1"},
+		{"op":"delete","id":"3"}
+	]}`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("9", "7").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	mock.ExpectPrepare(regexp.QuoteMeta(findExpenseSQL)).
+		ExpectQuery().
+		WithArgs("9", "7").
+		WillReturnError(sql.ErrNoRows)
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(batchDeleteSQL)).
+		ExpectExec().
+		WithArgs("3", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Batch(c)) {
+		assert.Equal(t, http.StatusMultiStatus, rec.Code)
+
+		var response types.ExpenseBatchResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, http.StatusNotFound, response.Results[0].Status)
+		assert.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+		assert.Contains(t, response.Results[1].Error, "Amount")
+		assert.Equal(t, http.StatusBadRequest, response.Results[2].Status)
+		assert.Equal(t, http.StatusNoContent, response.Results[3].Status)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,20 +1,45 @@
 package controllers
 
 import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/exports"
+	"github.com/walkmanrd/assessment/patches"
+	"github.com/walkmanrd/assessment/repositories"
 	"github.com/walkmanrd/assessment/services"
 	"github.com/walkmanrd/assessment/types"
 )
 
+const (
+	// mimeMergePatch is a content type for JSON merge patch (RFC 7396)
+	mimeMergePatch = "application/merge-patch+json"
+	// mimeJSONPatch is a content type for JSON patch (RFC 6902)
+	mimeJSONPatch = "application/json-patch+json"
+)
+
 // ExpenseController is a struct for expense controller
 type ExpenseController struct {
-	expenseRequest types.ExpenseRequest
 	expenseService services.ExpenseService
 }
 
+// NewExpenseController is a function to create new expense controller whose requests share the connection pool of db
+func NewExpenseController(db *sql.DB) *ExpenseController {
+	return &ExpenseController{
+		expenseService: *services.NewExpenseService(*repositories.NewExpenseRepository(db), *repositories.NewCategoryRepository(db), *repositories.NewAttachmentRepository(db), nil),
+	}
+}
+
 // bindAndValidateRequest is a function to bind and validate request
 func bindAndValidateRequest(c echo.Context, req interface{}) error {
 	if err := c.Bind(req); err != nil {
@@ -26,69 +51,524 @@ func bindAndValidateRequest(c echo.Context, req interface{}) error {
 	return nil
 }
 
+// importSource is a function to open the CSV of an import from a multipart file field or the raw request body
+func importSource(e echo.Context) (io.ReadCloser, error) {
+	contentType, _, _ := mime.ParseMediaType(e.Request().Header.Get(echo.HeaderContentType))
+	if contentType != echo.MIMEMultipartForm {
+		return e.Request().Body, nil
+	}
+
+	file, err := e.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	return file.Open()
+}
+
+// validateRow is a function to validate a request with the echo validator returning only the validation message
+func validateRow(e echo.Context) func(interface{}) error {
+	return func(req interface{}) error {
+		err := e.Validate(req)
+		if httpError, ok := err.(*echo.HTTPError); ok {
+			return fmt.Errorf("%v", httpError.Message)
+		}
+		return err
+	}
+}
+
+// currentUserID is a function to get the id of the authenticated user owning the expenses
+func currentUserID(e echo.Context) (string, bool) {
+	user, ok := auth.CurrentUser(e)
+	return user.ID, ok
+}
+
+// currentActor is a function to get the authenticated user and the id of the request making a change
+func currentActor(e echo.Context) (types.Actor, bool) {
+	user, ok := auth.CurrentUser(e)
+
+	requestID := e.Response().Header().Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		requestID = e.Request().Header.Get(echo.HeaderXRequestID)
+	}
+
+	return types.Actor{ID: user.ID, RequestID: requestID}, ok
+}
+
 // GET /expenses
 // Index is a function to get all expenses
 func (c *ExpenseController) Index(e echo.Context) error {
-	expenses, err := c.expenseService.Gets()
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	var query types.ExpenseQuery
+
+	if err := bindAndValidateRequest(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	expenses, status, err := c.expenseService.Gets(e.Request().Context(), ownerID, query)
 	if err != nil {
-		return e.JSON(http.StatusInternalServerError, types.Error{Message: err.Error()})
+		return e.JSON(status, types.Error{Message: err.Error()})
 	}
 
 	return e.JSON(http.StatusOK, expenses)
 }
 
+// GET /expenses/export
+// Export is a function to download the expenses matching the list filters as csv, ndjson or xlsx
+func (c *ExpenseController) Export(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	var query types.ExpenseQuery
+
+	if err := bindAndValidateRequest(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	format := e.QueryParam("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	buffer := bufio.NewWriter(e.Response())
+	encoder, err := exports.NewEncoder(format, buffer)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	e.Response().Header().Set(echo.HeaderContentType, exports.ContentType(format))
+	e.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", exports.Filename(format, time.Now())))
+
+	if status, err := c.expenseService.Export(e.Request().Context(), ownerID, query, encoder); err != nil {
+		// once rows were flushed the status is sent, the connection is dropped so the client can't take
+		// the download cut short for a complete file
+		if e.Response().Committed {
+			e.Logger().Error(err)
+			panic(http.ErrAbortHandler)
+		}
+		// the error is answered as JSON, not as the file the download headers announced
+		e.Response().Header().Del(echo.HeaderContentType)
+		e.Response().Header().Del(echo.HeaderContentDisposition)
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return buffer.Flush()
+}
+
+// GET /expenses/search
+// Search is a function to find expenses by the words of their title, note and tags
+func (c *ExpenseController) Search(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	var query types.ExpenseSearchQuery
+
+	if err := bindAndValidateRequest(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	result, status, err := c.expenseService.Search(e.Request().Context(), ownerID, query)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
+
 // GET /expenses/:id
 // Show is a function to get an expense by id
 func (c *ExpenseController) Show(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
 	id := e.Param("id")
 
 	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
 		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
 	}
 
-	expense, status, err := c.expenseService.GetById(id)
+	expense, status, err := c.expenseService.GetById(e.Request().Context(), ownerID, id)
 
 	if err != nil {
 		return e.JSON(status, types.Error{Message: err.Error()})
 	}
 
+	etag := expenseETag(expense)
+	e.Response().Header().Set(headerETag, etag)
+
+	if ifNoneMatch(e, etag) {
+		return e.NoContent(http.StatusNotModified)
+	}
+
 	return e.JSON(http.StatusOK, expense)
 }
 
 // POST /expenses
 // Store is a function to create a new expense
 func (c *ExpenseController) Store(e echo.Context) error {
-	if err := bindAndValidateRequest(e, &c.expenseRequest); err != nil {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+	ownerID, expenseService := actor.ID, c.expenseService.WithActor(actor)
+
+	var expenseRequest types.ExpenseRequest
+
+	if err := bindAndValidateRequest(e, &expenseRequest); err != nil {
 		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
 	}
 
-	expense, err := c.expenseService.Create(c.expenseRequest)
+	expense, status, err := expenseService.Create(e.Request().Context(), ownerID, expenseRequest)
 
 	if err != nil {
-		return e.JSON(http.StatusInternalServerError, types.Error{Message: err.Error()})
+		return e.JSON(status, types.Error{Message: err.Error()})
 	}
 
+	e.Response().Header().Set(headerETag, expenseETag(expense))
 	return e.JSON(http.StatusCreated, expense)
 }
 
+// POST /expenses/batch
+// Batch is a function to apply create, update and delete operations atomically or best effort
+func (c *ExpenseController) Batch(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+	ownerID, expenseService := actor.ID, c.expenseService.WithActor(actor)
+
+	var batchRequest types.ExpenseBatchRequest
+
+	if err := bindAndValidateRequest(e, &batchRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	response, status, err := expenseService.Batch(e.Request().Context(), ownerID, batchRequest, validateRow(e))
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(status, response)
+}
+
+// POST /expenses/import
+// Import is a function to create expenses from an uploaded CSV file in a single transaction
+func (c *ExpenseController) Import(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+	ownerID, expenseService := actor.ID, c.expenseService.WithActor(actor)
+
+	var query types.ExpenseImportQuery
+
+	if err := (&echo.DefaultBinder{}).BindQueryParams(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+	if err := e.Validate(&query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	source, err := importSource(e)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+	defer source.Close()
+
+	report, status, err := expenseService.Import(e.Request().Context(), ownerID, source, query, validateRow(e))
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(status, report)
+}
+
 // PUT /expenses/:id
 // Update is a function to get an expense by id
 func (c *ExpenseController) Update(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+	ownerID, expenseService := actor.ID, c.expenseService.WithActor(actor)
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	versions, status, err := ifMatchVersions(e)
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	var expenseRequest types.ExpenseRequest
+
+	if err := bindAndValidateRequest(e, &expenseRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	newExpense, status, err := expenseService.UpdateById(e.Request().Context(), ownerID, id, expenseRequest, versions)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	e.Response().Header().Set(headerETag, expenseETag(newExpense))
+	return e.JSON(http.StatusOK, newExpense)
+}
+
+// PATCH /expenses/:id
+// Patch is a function to partially update an expense by id with a merge patch or a JSON patch
+func (c *ExpenseController) Patch(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+	ownerID, expenseService := actor.ID, c.expenseService.WithActor(actor)
+
 	id := e.Param("id")
 
 	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
 		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
 	}
 
-	if err := bindAndValidateRequest(e, &c.expenseRequest); err != nil {
+	var applyPatch func([]byte, []byte) ([]byte, error)
+	contentType, _, _ := mime.ParseMediaType(e.Request().Header.Get(echo.HeaderContentType))
+
+	switch contentType {
+	case mimeMergePatch:
+		applyPatch = patches.MergePatch
+	case mimeJSONPatch:
+		applyPatch = patches.JSONPatch
+	default:
+		return e.JSON(http.StatusUnsupportedMediaType, types.Error{Message: "unsupported patch content type"})
+	}
+
+	versions, status, err := ifMatchVersions(e)
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	patch, err := io.ReadAll(e.Request().Body)
+	if err != nil {
 		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
 	}
 
-	newExpense, err := c.expenseService.UpdateById(id, c.expenseRequest)
+	expense, status, err := expenseService.GetById(e.Request().Context(), ownerID, id)
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
 
+	document, err := json.Marshal(types.ExpenseRequest{
+		Title:      expense.Title,
+		Amount:     expense.Amount,
+		Currency:   expense.Currency,
+		Note:       expense.Note,
+		Tags:       expense.Tags,
+		CategoryID: expense.CategoryID,
+		SpentAt:    expense.SpentAt,
+	})
 	if err != nil {
 		return e.JSON(http.StatusInternalServerError, types.Error{Message: err.Error()})
 	}
 
+	patched, err := applyPatch(document, patch)
+	switch {
+	case errors.Is(err, patches.ErrPatchConflict):
+		return e.JSON(http.StatusConflict, types.Error{Message: err.Error()})
+	case err != nil:
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	var expenseRequest types.ExpenseRequest
+	if err := json.Unmarshal(patched, &expenseRequest); err != nil {
+		return e.JSON(http.StatusUnprocessableEntity, types.Error{Message: err.Error()})
+	}
+	if err := e.Validate(&expenseRequest); err != nil {
+		return e.JSON(http.StatusUnprocessableEntity, types.Error{Message: err.Error()})
+	}
+
+	// the patch was applied to the version just read so only that version may be overwritten
+	if versions == nil || containsVersion(versions, expense.Version) {
+		versions = []int{expense.Version}
+	} else {
+		return e.JSON(http.StatusPreconditionFailed, types.Error{Message: "expense has been modified"})
+	}
+
+	newExpense, status, err := expenseService.UpdateById(e.Request().Context(), ownerID, id, expenseRequest, versions)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	e.Response().Header().Set(headerETag, expenseETag(newExpense))
 	return e.JSON(http.StatusOK, newExpense)
 }
+
+// DELETE /expenses/:id
+// Delete is a function to soft delete an expense by id
+func (c *ExpenseController) Delete(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+	ownerID, expenseService := actor.ID, c.expenseService.WithActor(actor)
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	if status, err := expenseService.DeleteById(e.Request().Context(), ownerID, id); err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// POST /expenses/:id/restore
+// Restore is a function to restore a soft deleted expense by id
+func (c *ExpenseController) Restore(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+	ownerID, expenseService := actor.ID, c.expenseService.WithActor(actor)
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	expense, status, err := expenseService.RestoreById(e.Request().Context(), ownerID, id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	e.Response().Header().Set(headerETag, expenseETag(expense))
+	return e.JSON(http.StatusOK, expense)
+}
+
+// DELETE /expenses/:id/purge
+// Purge is a function to permanently delete an expense of any owner by id
+func (c *ExpenseController) Purge(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+	expenseService := c.expenseService.WithActor(actor)
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	if status, err := expenseService.PurgeById(e.Request().Context(), id); err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// POST /expenses/:id/submit
+// Submit is a function to send a draft expense for approval
+func (c *ExpenseController) Submit(e echo.Context) error {
+	return c.transition(e, services.ActionSubmit)
+}
+
+// POST /expenses/:id/reopen
+// Reopen is a function to turn a rejected expense back into an editable draft
+func (c *ExpenseController) Reopen(e echo.Context) error {
+	return c.transition(e, services.ActionReopen)
+}
+
+// POST /expenses/:id/approve
+// Approve is a function to approve a submitted expense
+func (c *ExpenseController) Approve(e echo.Context) error {
+	return c.transition(e, services.ActionApprove)
+}
+
+// POST /expenses/:id/reject
+// Reject is a function to send a submitted expense back to its owner with a comment
+func (c *ExpenseController) Reject(e echo.Context) error {
+	return c.transition(e, services.ActionReject)
+}
+
+// POST /expenses/:id/reimburse
+// Reimburse is a function to mark an approved expense as paid back
+func (c *ExpenseController) Reimburse(e echo.Context) error {
+	return c.transition(e, services.ActionReimburse)
+}
+
+// GET /expenses/:id/transitions
+// Transitions is a function to get the status history of an expense
+func (c *ExpenseController) Transitions(e echo.Context) error {
+	user, ok := auth.CurrentUser(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	canReview := auth.DefaultPolicy.Grants(user.Roles, auth.PermissionApprove)
+	transitions, status, err := c.expenseService.Transitions(e.Request().Context(), user.ID, canReview, id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, transitions)
+}
+
+// transition is a function to apply a workflow action to an expense by id on behalf of the current user
+func (c *ExpenseController) transition(e echo.Context, action string) error {
+	user, ok := auth.CurrentUser(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	var transitionRequest types.ExpenseTransitionRequest
+
+	if err := bindAndValidateRequest(e, &transitionRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	actor, _ := currentActor(e)
+	canReview := auth.DefaultPolicy.Grants(user.Roles, auth.PermissionApprove)
+	expense, status, err := c.expenseService.WithActor(actor).Transition(e.Request().Context(), user.ID, canReview, id, action, transitionRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	e.Response().Header().Set(headerETag, expenseETag(expense))
+	return e.JSON(http.StatusOK, expense)
+}
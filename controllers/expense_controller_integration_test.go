@@ -6,18 +6,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/middlewares"
+	"github.com/walkmanrd/assessment/migrations"
 	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/types"
 	"github.com/walkmanrd/assessment/validators"
 
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -26,17 +31,27 @@ import (
 const serverPort = 2565
 
 func SetupSuite() *echo.Echo {
+	db := configs.ConnectDatabase()
+	if _, err := migrations.MigrateUp(db); err != nil {
+		log.Fatal("can't migrate database: ", err)
+	}
+	db.Close()
+
+	// requestDB is a connection pool shared by the requests of the suite
+	requestDB := configs.ConnectDatabase()
+
 	eh := echo.New()
-	eh.Validator = &validators.CustomValidator{Validator: validator.New()}
+	eh.Validator = validators.NewCustomValidator()
 	eh.Use(middleware.Logger())
 	eh.Use(middleware.Recover())
-	var expenseController ExpenseController
+	expenseController := NewExpenseController(requestDB)
 
 	// Setting up routes
-	eh.GET("/expenses", expenseController.Index)
-	eh.GET("/expenses/:id", expenseController.Show)
-	eh.POST("/expenses", expenseController.Store)
-	eh.PUT("/expenses/:id", expenseController.Update)
+	g := eh.Group("/expenses", middlewares.AuthHeader(requestDB), middlewares.Authorize(auth.DefaultPolicy))
+	g.GET("", expenseController.Index)
+	g.GET("/:id", expenseController.Show)
+	g.POST("", expenseController.Store)
+	g.PUT("/:id", expenseController.Update)
 
 	go func(e *echo.Echo) {
 		e.Start(fmt.Sprintf(":%d", serverPort))
@@ -66,6 +81,7 @@ func seedExpense(t *testing.T) models.Expense {
 	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%d/expenses", serverPort), strings.NewReader(reqBody))
 	assert.Nil(t, err)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Authorization", os.Getenv("AUTH_TOKEN"))
 	client := http.Client{}
 	resp, err := client.Do(req)
 
@@ -83,7 +99,7 @@ func seedExpense(t *testing.T) models.Expense {
 
 	if assert.NotEqual(t, 0, expense.ID) {
 		assert.Equal(t, "strawberry smoothie", expense.Title)
-		assert.Equal(t, float64(79), float64(expense.Amount))
+		assert.Equal(t, "79", expense.Amount.String())
 		assert.Equal(t, "night market promotion discount 10 bath", expense.Note)
 		assert.Equal(t, pq.StringArray{"food", "beverage"}, expense.Tags)
 	}
@@ -96,6 +112,7 @@ func getExpenseById(t *testing.T, id string) models.Expense {
 	assert.NoError(t, err)
 
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Authorization", os.Getenv("AUTH_TOKEN"))
 	client := http.Client{}
 
 	resp, err := client.Do(req)
@@ -112,7 +129,7 @@ func getExpenseById(t *testing.T, id string) models.Expense {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 		assert.NotEqual(t, 0, expense.ID)
 		assert.Equal(t, "strawberry smoothie", expense.Title)
-		assert.Equal(t, float64(79), float64(expense.Amount))
+		assert.Equal(t, "79", expense.Amount.String())
 		assert.Equal(t, "night market promotion discount 10 bath", expense.Note)
 		assert.Equal(t, pq.StringArray{"food", "beverage"}, expense.Tags)
 	}
@@ -160,6 +177,7 @@ func TestGetAllExpense(t *testing.T) {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%d/expenses", serverPort), nil)
 	assert.NoError(t, err)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Authorization", os.Getenv("AUTH_TOKEN"))
 	client := http.Client{}
 
 	// act
@@ -169,8 +187,9 @@ func TestGetAllExpense(t *testing.T) {
 	resBody, err2 := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
 
-	var es []models.Expense
-	json.Unmarshal(resBody, &es)
+	var list types.ExpenseList
+	json.Unmarshal(resBody, &list)
+	es := list.Data
 
 	// assertions
 	if assert.NoError(t, err2) {
@@ -178,7 +197,7 @@ func TestGetAllExpense(t *testing.T) {
 		assert.NotEqual(t, 0, len(es))
 		assert.NotEqual(t, 0, es[0].ID)
 		assert.Equal(t, "strawberry smoothie", es[0].Title)
-		assert.Equal(t, float64(79), float64(es[0].Amount))
+		assert.Equal(t, "79", es[0].Amount.String())
 		assert.Equal(t, "night market promotion discount 10 bath", es[0].Note)
 		assert.Equal(t, pq.StringArray{"food", "beverage"}, es[0].Tags)
 	}
@@ -207,6 +226,7 @@ func TestUpdateUserByID(t *testing.T) {
 	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://localhost:%d/expenses/"+newExpense.ID, serverPort), strings.NewReader(reqBody))
 	assert.NoError(t, err)
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Authorization", os.Getenv("AUTH_TOKEN"))
 	client := http.Client{}
 	respUpdate, err := client.Do(req)
 
@@ -224,7 +244,7 @@ func TestUpdateUserByID(t *testing.T) {
 
 	assert.NotEqual(t, 0, lastExpense.ID)
 	assert.Equal(t, "strawberry smoothie for update", lastExpense.Title)
-	assert.Equal(t, float64(80), float64(lastExpense.Amount))
+	assert.Equal(t, "80", lastExpense.Amount.String())
 	assert.Equal(t, "night market promotion discount 10 bath for update", lastExpense.Note)
 	assert.Equal(t, pq.StringArray{"food", "beverage", "for update"}, lastExpense.Tags)
 
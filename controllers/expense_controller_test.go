@@ -3,49 +3,66 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/models"
 	"github.com/walkmanrd/assessment/repositories"
 	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
 	"github.com/walkmanrd/assessment/validators"
 )
 
-var requestBody = `{"id":"1","title":"strawberry smoothie","amount":79,"note":"night market promotion discount 10 bath","tags":["food","beverage"]}`
+var testTime = time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
 
-func setupTest(db *sql.DB) *ExpenseController {
-	expenseRepository := repositories.NewExpenseRepository(db)
-	expenseService := services.NewExpenseService(*expenseRepository)
+// findExpenseSQL is a statement to get an owner's expense by id
+const findExpenseSQL = `SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL`
 
-	expenseController := &ExpenseController{
-		expenseService: *expenseService,
-	}
+var testUser = models.User{ID: "7", Name: "tester", Roles: []string{"editor"}}
+
+var requestBody = `{"id":"1","title":"strawberry smoothie","amount":79,"currency":"THB","note":"night market promotion discount 10 bath","tags":["food","beverage"],"spent_at":"2022-01-02","created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z","status":"draft"}`
 
-	return expenseController
+// expectActor is a function to expect the transaction tagging the audit events of a change with a user
+func expectActor(mock sqlmock.Sqlmock, user models.User) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT set_config('app.actor_id', $1, true), set_config('app.request_id', $2, true)`)).
+		WithArgs(user.ID, "").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func setupTest(db *sql.DB) *ExpenseController {
+	return NewExpenseController(db)
 }
 
 func TestCreateExpense(t *testing.T) {
 	e := echo.New()
-	e.Validator = &validators.CustomValidator{Validator: validator.New()}
+	e.Validator = validators.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(requestBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
 
-	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).AddRow("1", "strawberry smoothie", 79.0, "night market promotion discount 10 bath", `{"food","beverage"}`)
+	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).AddRow("1", "strawberry smoothie", 79.0, "THB", "night market promotion discount 10 bath", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 1)
 	db, mock, err := sqlmock.New()
-	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO expenses (id, title, amount, note, tags) values (DEFAULT, $1, $2, $3, $4)
-	RETURNING id, title, amount, note, tags`)).
-		WithArgs("strawberry smoothie", 79.0, "night market promotion discount 10 bath", `{"food","beverage"}`).
+	expectActor(mock, testUser)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO expenses (id, owner_id, title, amount, currency, note, tags, category_id, spent_at) values (DEFAULT, $1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version`)).
+		WithArgs("7", "strawberry smoothie", "79", "THB", "night market promotion discount 10 bath", `{"food","beverage"}`, nil, "2022-01-02").
 		WillReturnRows(mockRows)
+	mock.ExpectCommit()
 
 	if err != nil {
 		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
@@ -61,17 +78,18 @@ func TestCreateExpense(t *testing.T) {
 
 func TestGetExpenseById(t *testing.T) {
 	e := echo.New()
-	e.Validator = &validators.CustomValidator{Validator: validator.New()}
+	e.Validator = validators.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodGet, "/expenses/1", strings.NewReader(""))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 
-	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).AddRow("1", "strawberry smoothie get by id", 99.0, "night market promotion discount 10 bath get by id", `{"food","beverage","get by id"}`)
+	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).AddRow("1", "strawberry smoothie get by id", 99.0, "THB", "night market promotion discount 10 bath get by id", `{"food","beverage","get by id"}`, nil, "2022-01-02", testTime, testTime, "draft", 1)
 
 	db, mock, err := sqlmock.New()
-	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, note, tags FROM expenses WHERE id = $1`)).
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL`)).
+		WillBeClosed().
 		ExpectQuery().
-		WithArgs("1").
+		WithArgs("1", "7").
 		WillReturnRows(mockRows)
 
 	if err != nil {
@@ -79,44 +97,49 @@ func TestGetExpenseById(t *testing.T) {
 	}
 
 	expenseRepository := repositories.NewExpenseRepository(db)
-	expenseService := services.NewExpenseService(*expenseRepository)
+	expenseService := services.NewExpenseService(*expenseRepository, *repositories.NewCategoryRepository(db), *repositories.NewAttachmentRepository(db), nil)
 
 	expenseController := &ExpenseController{
 		expenseService: *expenseService,
 	}
 	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
 	c.SetPath("/expenses/:id")
 	c.SetParamNames("id")
 	c.SetParamValues("1")
 
-	var expectTestGetById = `{"id":"1","title":"strawberry smoothie get by id","amount":99,"note":"night market promotion discount 10 bath get by id","tags":["food","beverage","get by id"]}`
+	var expectTestGetById = `{"id":"1","title":"strawberry smoothie get by id","amount":99,"currency":"THB","note":"night market promotion discount 10 bath get by id","tags":["food","beverage","get by id"],"spent_at":"2022-01-02","created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z","status":"draft"}`
 
 	if assert.NoError(t, expenseController.Show(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, expectTestGetById, strings.TrimSpace(rec.Body.String()))
 	}
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateExpenseById(t *testing.T) {
 	e := echo.New()
-	e.Validator = &validators.CustomValidator{Validator: validator.New()}
-	var updateBody = `{"id":"1","title":"strawberry smoothie update","amount":100,"note":"night market promotion discount 10 bath update","tags":["food","beverage","update"]}`
+	e.Validator = validators.NewCustomValidator()
+	var updateBody = `{"id":"1","title":"strawberry smoothie update","amount":100,"currency":"THB","note":"night market promotion discount 10 bath update","tags":["food","beverage","update"],"spent_at":"2022-01-02","created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z","status":"draft"}`
 	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(updateBody))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
 	c.SetPath("/expenses/:id")
 	c.SetParamNames("id")
 	c.SetParamValues("1")
 
-	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).
-		AddRow("1", "strawberry smoothie update", 100, "night market promotion discount 10 bath update", `{"food","beverage","update"}`)
+	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+		AddRow("1", "strawberry smoothie update", 100, "THB", "night market promotion discount 10 bath update", `{"food","beverage","update"}`, nil, "2022-01-02", testTime, testTime, "draft", 1)
 	db, mock, err := sqlmock.New()
 
-	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET title = $2, amount = $3, note = $4, tags = $5 WHERE id = $1 RETURNING id, title, amount, note, tags;`)).
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET title = $3, amount = $4, currency = $5, note = $6, tags = $7, category_id = $8, spent_at = COALESCE($9, spent_at), updated_at = NOW(), version = version + 1 WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft' RETURNING id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version;`)).
 		ExpectQuery().
-		WithArgs("1", "strawberry smoothie update", 100.0, "night market promotion discount 10 bath update", `{"food","beverage","update"}`).
+		WithArgs("1", "7", "strawberry smoothie update", "100", "THB", "night market promotion discount 10 bath update", `{"food","beverage","update"}`, nil, "2022-01-02").
 		WillReturnRows(mockRows)
+	mock.ExpectCommit()
 
 	if err != nil {
 		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
@@ -131,30 +154,743 @@ func TestUpdateExpenseById(t *testing.T) {
 
 func TestGetExpenses(t *testing.T) {
 	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodGet, "/expenses", strings.NewReader(""))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 
-	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).
-		AddRow("1", "strawberry smoothie 1", 71, "night market promotion discount 10 bath 1", `{"food","beverage","1"}`).
-		AddRow("2", "strawberry smoothie 2", 72, "night market promotion discount 10 bath 2", `{"food","beverage","2"}`)
+	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+		AddRow("1", "strawberry smoothie 1", 71, "THB", "night market promotion discount 10 bath 1", `{"food","beverage","1"}`, nil, "2022-01-02", testTime, testTime, "draft", 1).
+		AddRow("2", "strawberry smoothie 2", 72, "THB", "night market promotion discount 10 bath 2", `{"food","beverage","2"}`, nil, "2022-01-02", testTime, testTime, "draft", 1)
 
 	db, mock, err := sqlmock.New()
-	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, note, tags FROM expenses ORDER BY id ASC`)).
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE owner_id = $1 AND deleted_at IS NULL ORDER BY id ASC LIMIT $2`)).
+		WillBeClosed().
 		ExpectQuery().
+		WithArgs("7", 21).
 		WillReturnRows(mockRows)
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT COUNT(*) FROM expenses WHERE owner_id = $1 AND deleted_at IS NULL`)).
+		WillBeClosed().
+		ExpectQuery().
+		WithArgs("7").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 	if err != nil {
 		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
 	}
 
-	var expectTestGetAll = `[{"id":"1","title":"strawberry smoothie 1","amount":71,"note":"night market promotion discount 10 bath 1","tags":["food","beverage","1"]},{"id":"2","title":"strawberry smoothie 2","amount":72,"note":"night market promotion discount 10 bath 2","tags":["food","beverage","2"]}]`
+	var expectTestGetAll = `{"data":[{"id":"1","title":"strawberry smoothie 1","amount":71,"currency":"THB","note":"night market promotion discount 10 bath 1","tags":["food","beverage","1"],"spent_at":"2022-01-02","created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z","status":"draft"},{"id":"2","title":"strawberry smoothie 2","amount":72,"currency":"THB","note":"night market promotion discount 10 bath 2","tags":["food","beverage","2"],"spent_at":"2022-01-02","created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z","status":"draft"}],"next_cursor":"","total":2}`
 
 	expenseController := setupTest(db)
 	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
 	c.SetPath("/expenses")
 
 	if assert.NoError(t, expenseController.Index(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, expectTestGetAll, strings.TrimSpace(rec.Body.String()))
 	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetExpensesWithFilterAndCursor(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?limit=1&tag=food&tag=beverage&tag_mode=all&min_amount=10&title=smoothie&spent_from=2022-01-01&spent_to=2022-01-31&sort=amount&order=desc", nil)
+	rec := httptest.NewRecorder()
+
+	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+		AddRow("2", "strawberry smoothie 2", 72, "THB", "note 2", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 1).
+		AddRow("1", "strawberry smoothie 1", 71, "THB", "note 1", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 1)
+
+	db, mock, err := sqlmock.New()
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE owner_id = $1 AND deleted_at IS NULL AND tags @> $2 AND amount >= $3 AND title ILIKE $4 AND spent_at >= $5 AND spent_at <= $6 ORDER BY amount DESC, id DESC LIMIT $7`)).
+		ExpectQuery().
+		WithArgs("7", `{"food","beverage"}`, "10", "%smoothie%", "2022-01-01", "2022-01-31", 2).
+		WillReturnRows(mockRows)
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT COUNT(*) FROM expenses WHERE owner_id = $1 AND deleted_at IS NULL AND tags @> $2 AND amount >= $3 AND title ILIKE $4 AND spent_at >= $5 AND spent_at <= $6`)).
+		ExpectQuery().
+		WithArgs("7", `{"food","beverage"}`, "10", "%smoothie%", "2022-01-01", "2022-01-31").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expenseController := setupTest(db)
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses")
+
+	if assert.NoError(t, expenseController.Index(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var list struct {
+			Data       []map[string]interface{} `json:"data"`
+			NextCursor string                   `json:"next_cursor"`
+			Total      int                      `json:"total"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+		assert.Len(t, list.Data, 1)
+		assert.Equal(t, "2", list.Data[0]["id"])
+		assert.NotEmpty(t, list.NextCursor)
+		assert.Equal(t, 2, list.Total)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetExpensesInvalidSpentAt(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?spent_from=01/02/2022", nil)
+	rec := httptest.NewRecorder()
+
+	expenseController := setupTest(nil)
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses")
+
+	if assert.NoError(t, expenseController.Index(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestGetExpensesInvalidCursor(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/expenses?cursor=not-a-cursor", nil)
+	rec := httptest.NewRecorder()
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expenseController := setupTest(db)
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses")
+
+	if assert.NoError(t, expenseController.Index(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"message":"invalid cursor"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestDeleteExpenseById(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft'`)).
+		ExpectExec().
+		WithArgs("1", "7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Delete(c)) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestPurgeExpenseOfAnotherOwner(t *testing.T) {
+	admin := models.User{ID: "9", Name: "admin", Roles: []string{auth.RoleAdmin}}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, admin)
+	c.SetPath("/expenses/:id/purge")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM attachments WHERE expense_id = $1`)).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expense_id", "filename", "content_type", "size", "checksum", "created_at"}))
+	expectActor(mock, admin)
+	mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM expenses WHERE id = $1`)).
+		ExpectExec().
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Purge(c)) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteExpenseByIdNotFound(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("9")
+
+	db, mock, err := sqlmock.New()
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft'`)).
+		ExpectExec().
+		WithArgs("9", "7").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectPrepare(regexp.QuoteMeta(findExpenseSQL)).
+		ExpectQuery().
+		WithArgs("9", "7").
+		WillReturnError(sql.ErrNoRows)
+
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Delete(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, `{"message":"expense not found"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestDeleteExpenseByIdLocked(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft'`)).
+		ExpectExec().
+		WithArgs("1", "7").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectPrepare(regexp.QuoteMeta(findExpenseSQL)).
+		ExpectQuery().
+		WithArgs("1", "7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "taxi", 250, "THB", "client visit", `{"travel"}`, nil, "2022-01-02", testTime, testTime, "submitted", 2))
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Delete(c)) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, `{"message":"expense is submitted and can no longer be edited"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreExpenseById(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id/restore")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	mockRows := sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+		AddRow("1", "strawberry smoothie", 79.0, "THB", "night market promotion discount 10 bath", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 1)
+	db, mock, err := sqlmock.New()
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET deleted_at = NULL, updated_at = NOW(), version = version + 1 WHERE id = $1 AND owner_id = $2 AND deleted_at IS NOT NULL RETURNING id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version;`)).
+		ExpectQuery().
+		WithArgs("1", "7").
+		WillReturnRows(mockRows)
+	mock.ExpectCommit()
+
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Restore(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, requestBody, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestPatchExpenseById(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`[{"op":"add","path":"/tags/-","value":"update"}]`))
+	req.Header.Set(echo.HeaderContentType, "application/json-patch+json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL`)).
+		ExpectQuery().
+		WithArgs("1", "7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "strawberry smoothie", 79.0, "THB", "night market promotion discount 10 bath", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 1))
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET title = $3, amount = $4, currency = $5, note = $6, tags = $7, category_id = $8, spent_at = COALESCE($9, spent_at), updated_at = NOW(), version = version + 1 WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft' AND version = ANY($10) RETURNING id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version;`)).
+		ExpectQuery().
+		WithArgs("1", "7", "strawberry smoothie", "79", "THB", "night market promotion discount 10 bath", `{"food","beverage","update"}`, nil, "2022-01-02", "{1}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "strawberry smoothie", 79.0, "THB", "night market promotion discount 10 bath", `{"food","beverage","update"}`, nil, "2022-01-02", testTime, testTime, "draft", 2))
+	mock.ExpectCommit()
+
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Patch(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"id":"1","title":"strawberry smoothie","amount":79,"currency":"THB","note":"night market promotion discount 10 bath","tags":["food","beverage","update"],"spent_at":"2022-01-02","created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z","status":"draft"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestPatchExpenseByIdInvalidResult(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"title":null}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL`)).
+		ExpectQuery().
+		WithArgs("1", "7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "strawberry smoothie", 79.0, "THB", "night market promotion discount 10 bath", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 1))
+
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Patch(c)) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestGetExpenseByIdNotModified(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+	req.Header.Set("If-None-Match", `"3"`)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL`)).
+		ExpectQuery().
+		WithArgs("1", "7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "strawberry smoothie", 79.0, "THB", "night market promotion discount 10 bath", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 3))
+
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Show(c)) {
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.Empty(t, rec.Body.String())
+	}
+}
+
+func TestUpdateExpenseByIdPreconditionFailed(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(requestBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("If-Match", `"1"`)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE expenses SET title = $3, amount = $4, currency = $5, note = $6, tags = $7, category_id = $8, spent_at = COALESCE($9, spent_at), updated_at = NOW(), version = version + 1 WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft' AND version = ANY($10) RETURNING id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version;`)).
+		ExpectQuery().
+		WithArgs("1", "7", "strawberry smoothie", "79", "THB", "night market promotion discount 10 bath", `{"food","beverage"}`, nil, "2022-01-02", "{1}").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL`)).
+		ExpectQuery().
+		WithArgs("1", "7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "strawberry smoothie", 79.0, "THB", "night market promotion discount 10 bath", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 2))
+
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Update(c)) {
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Equal(t, `{"message":"expense has been modified"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestUpdateExpenseByIdPreconditionRequired(t *testing.T) {
+	t.Setenv("ETAG_STRICT", "true")
+
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(requestBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Update(c)) {
+		assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	}
+}
+
+func TestCreateExpenseInvalidCurrencyDecimals(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(`{"title":"ramen","amount":"980.5","currency":"JPY","note":"tokyo","tags":["food"],"spent_at":"2022-01-02","created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z","status":"draft"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Store(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Amount")
+	}
+}
+
+func TestGetExpenseByIdUnauthenticated(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Show(c)) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetExpenseByIdTimeout(t *testing.T) {
+	t.Setenv("QUERY_TIMEOUT_READ", "10ms")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectPrepare(regexp.QuoteMeta(findExpenseSQL)).
+		ExpectQuery().
+		WithArgs("1", "7").
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Show(c)) {
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		assert.Equal(t, `{"message":"query timed out"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestCreateExpenseCategoryCheckTimeout(t *testing.T) {
+	t.Setenv("QUERY_TIMEOUT_WRITE", "10ms")
+
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	body := `{"title":"strawberry smoothie","amount":79,"note":"night market","tags":["food"],"category_id":"3"}`
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, parent_id FROM categories WHERE id = $1`)).
+		WithArgs("3").
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow("3", "food", nil))
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Store(c)) {
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		assert.Equal(t, `{"message":"query timed out"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestGetExpenseByIdCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/expenses/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Show(c)) {
+		assert.Equal(t, services.StatusClientClosedRequest, rec.Code)
+		assert.Equal(t, `{"message":"request canceled"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestExportExpenses(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/export?format=csv&tag=food", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version FROM expenses WHERE owner_id = $1 AND deleted_at IS NULL AND tags && $2 ORDER BY id ASC`)).
+		WithArgs("7", `{"food"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "strawberry smoothie", 79, "THB", "night market", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 1))
+
+	expenseController := setupTest(db)
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	if assert.NoError(t, expenseController.Export(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		assert.Regexp(t, `^attachment; filename="expenses-\d{8}-\d{6}\.csv"$`, rec.Header().Get(echo.HeaderContentDisposition))
+		assert.Equal(t, "id,title,amount,currency,note,tags,category_id,spent_at,created_at,updated_at,status\n"+
+			"1,strawberry smoothie,79,THB,night market,food|beverage,,2022-01-02,2022-01-02T03:04:05Z,2022-01-02T03:04:05Z,draft\n", rec.Body.String())
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportExpensesQueryError(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/export?format=csv", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM expenses WHERE owner_id = $1 AND deleted_at IS NULL ORDER BY id ASC`)).
+		WithArgs("7").
+		WillReturnError(errors.New("connection reset"))
+
+	expenseController := setupTest(db)
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	if assert.NoError(t, expenseController.Export(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+		assert.Empty(t, rec.Header().Get(echo.HeaderContentDisposition))
+		assert.Equal(t, `{"message":"connection reset"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportExpensesUnknownFormat(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	rec := httptest.NewRecorder()
+
+	expenseController := setupTest(nil)
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/expenses/export?format=pdf", nil), rec)
+	auth.SetUser(c, testUser)
+
+	if assert.NoError(t, expenseController.Export(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, rec.Header().Get(echo.HeaderContentDisposition))
+	}
+}
+
+func TestSearchExpenses(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/search?q=Taxi,+air&tag=Travel&limit=5", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM expenses, to_tsquery('english', $3) AS q WHERE owner_id = $1 AND deleted_at IS NULL AND tags && $2 AND search @@ q
+	ORDER BY rank DESC, id DESC LIMIT $4 OFFSET $5`)).
+		WithArgs("7", `{"travel"}`, "taxi & air:*", 5, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version", "rank", "title", "note", "tags"}).
+			AddRow("3", "taxi to airport", 450, "THB", "late flight", `{"travel","taxi"}`, nil, "2022-03-04", testTime, testTime, "draft", 1,
+				0.61, "\ue000taxi\ue001 to \ue000airport\ue001", "late flight", `{"taxi"}`))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM expenses, to_tsquery('english', $3) AS q WHERE owner_id = $1 AND deleted_at IS NULL AND tags && $2 AND search @@ q`)).
+		WithArgs("7", `{"travel"}`, "taxi & air:*").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	expenseController := setupTest(db)
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	if assert.NoError(t, expenseController.Search(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var result types.ExpenseSearchResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, 1, result.Total)
+		if assert.Len(t, result.Data, 1) {
+			assert.Equal(t, "3", result.Data[0].Expense.ID)
+			assert.Equal(t, 0.61, result.Data[0].Rank)
+			assert.Equal(t, "<mark>taxi</mark> to <mark>airport</mark>", result.Data[0].Highlight.Title)
+			assert.Equal(t, []string{"taxi"}, result.Data[0].Highlight.Tags)
+		}
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchExpensesEscapesHighlight(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/expenses/search?q=taxi", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	title := `<img src=x onerror=alert(1)> taxi`
+	mock.ExpectQuery(regexp.QuoteMeta(`ts_headline('english', title, q, 'StartSel=` + "\ue000" + `, StopSel=` + "\ue001" + `, HighlightAll=true')`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version", "rank", "title", "note", "tags"}).
+			AddRow("3", title, 450, "THB", "a & b", `{"travel","<b onmouseover=alert(1)>taxi</b>"}`, nil, "2022-03-04", testTime, testTime, "draft", 1,
+				0.61, "<img src=x onerror=alert(1)> \ue000taxi\ue001", "a & b", `{"<b onmouseover=alert(1)>taxi</b>"}`))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM expenses`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	expenseController := setupTest(db)
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	if assert.NoError(t, expenseController.Search(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var result types.ExpenseSearchResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		if assert.Len(t, result.Data, 1) {
+			assert.Equal(t, title, result.Data[0].Expense.Title)
+			assert.Equal(t, "&lt;img src=x onerror=alert(1)&gt; <mark>taxi</mark>", result.Data[0].Highlight.Title)
+			assert.Equal(t, "a &amp; b", result.Data[0].Highlight.Note)
+			assert.Equal(t, []string{"&lt;b onmouseover=alert(1)&gt;taxi&lt;/b&gt;"}, result.Data[0].Highlight.Tags)
+		}
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchExpensesWithoutWords(t *testing.T) {
+	subtests := map[string]string{
+		"missing":     "/expenses/search",
+		"punctuation": "/expenses/search?q=%21%3F",
+	}
+	for name, target := range subtests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			e.Validator = validators.NewCustomValidator()
+			rec := httptest.NewRecorder()
+
+			expenseController := setupTest(nil)
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)
+			auth.SetUser(c, testUser)
+
+			if assert.NoError(t, expenseController.Search(c)) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			}
+		})
+	}
 }
@@ -0,0 +1,204 @@
+//go:build unit
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/types"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+var importInsertSQL = regexp.QuoteMeta(`INSERT INTO expenses (id, owner_id, title, amount, currency, note, tags, category_id, spent_at) values (DEFAULT, $1, $2, $3, $4, $5, $6, $7, $8)`)
+
+var importColumns = []string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}
+
+func TestImportExpenses(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	body := "Description;amount;note;tags;spent_at\n" +
+		"strawberry smoothie;79;night market;Food|Beverage;2022-01-02\n" +
+		"iPhone 14 Pro Max 1TB;66900.5;birthday gift;Gadget;2022-01-03\n"
+	req := httptest.NewRequest(http.MethodPost, "/expenses/import?delimiter=%3B&map=title:description", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	prepared := mock.ExpectPrepare(importInsertSQL)
+	prepared.ExpectQuery().
+		WithArgs("7", "strawberry smoothie", "79", "THB", "night market", `{"food","beverage"}`, nil, "2022-01-02").
+		WillReturnRows(sqlmock.NewRows(importColumns).AddRow("1", "strawberry smoothie", 79, "THB", "night market", `{"food","beverage"}`, nil, "2022-01-02", testTime, testTime, "draft", 1))
+	prepared.ExpectQuery().
+		WithArgs("7", "iPhone 14 Pro Max 1TB", "66900.5", "THB", "birthday gift", `{"gadget"}`, nil, "2022-01-03").
+		WillReturnRows(sqlmock.NewRows(importColumns).AddRow("2", "iPhone 14 Pro Max 1TB", 66900.5, "THB", "birthday gift", `{"gadget"}`, nil, "2022-01-03", testTime, testTime, "draft", 1))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Import(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var report types.ExpenseImportReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, 2, report.Total)
+		assert.Equal(t, 2, report.Succeeded)
+		assert.Equal(t, 2, report.Rows[0].Row)
+		assert.Equal(t, "1", report.Rows[0].Expense.ID)
+		assert.Equal(t, "2", report.Rows[1].Expense.ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportExpensesDryRun(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	body := "title,amount,note,tags\nstrawberry smoothie,79,night market,food\n"
+	req := httptest.NewRequest(http.MethodPost, "/expenses/import?dry_run=true", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(importInsertSQL).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows(importColumns).AddRow("1", "strawberry smoothie", 79, "THB", "night market", `{"food"}`, nil, "2022-01-02", testTime, testTime, "draft", 1))
+	mock.ExpectRollback()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Import(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"dry_run":true,"total":1,"succeeded":1,"failed":0,"rows":[{"row":2}]}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func importContext(body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPost, "/expenses/import", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	return c, rec
+}
+
+func TestImportExpensesRowViolatesConstraint(t *testing.T) {
+	c, rec := importContext("title,amount,note,tags\nstrawberry smoothie,79,night market,food\n")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(importInsertSQL).
+		ExpectQuery().
+		WillReturnError(&pq.Error{Code: "23514", Message: "new row violates check constraint"})
+	mock.ExpectRollback()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Import(c)) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"rows":[{"row":2,"error":"pq: new row violates check constraint"}]`)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportExpensesQueryCanceled(t *testing.T) {
+	c, rec := importContext("title,amount,note,tags\nstrawberry smoothie,79,night market,food\n")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(importInsertSQL).
+		ExpectQuery().
+		WillReturnError(&pq.Error{Code: "57014", Message: "canceling statement due to user request"})
+	mock.ExpectRollback()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Import(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), `"rows"`)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportExpensesInvalidRows(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	body := "title,amount,currency,note,tags\n" +
+		"strawberry smoothie,79,THB,night market,food\n" +
+		"ramen,abc,JPY,lunch,food\n" +
+		"sushi,1200.5,JPY,dinner,food\n" +
+		",10,THB,no title,food\n"
+	req := httptest.NewRequest(http.MethodPost, "/expenses/import", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Import(c)) {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+		var report types.ExpenseImportReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, 4, report.Total)
+		assert.Equal(t, 0, report.Succeeded)
+		assert.Equal(t, 3, report.Failed)
+		assert.Empty(t, report.Rows[0].Error)
+		assert.Equal(t, `invalid amount "abc"`, report.Rows[1].Error)
+		assert.Contains(t, report.Rows[2].Error, "Amount")
+		assert.Contains(t, report.Rows[3].Error, "Title")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportExpensesMissingColumn(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPost, "/expenses/import", strings.NewReader("title,amount,note\nramen,100,lunch\n"))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	expenseController := setupTest(nil)
+
+	if assert.NoError(t, expenseController.Import(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"message":"missing column tags"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
@@ -0,0 +1,205 @@
+//go:build unit
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+var (
+	findWithOwnerSQL  = regexp.QuoteMeta(`SELECT owner_id, id, title`)
+	transitionSQL     = regexp.QuoteMeta(`WITH updated AS (`)
+	ownerExpenseRows  = []string{"owner_id", "id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}
+	transitionColumns = []string{"id", "expense_id", "from_status", "to_status", "actor_id", "name", "comment", "created_at"}
+	approverUser      = models.User{ID: "8", Name: "approver", Roles: []string{auth.RoleApprover}}
+)
+
+func workflowRequest(user models.User, action string, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, user)
+	c.SetPath("/expenses/:id/" + action)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	return c, rec
+}
+
+func expectOwnedExpense(mock sqlmock.Sqlmock, ownerID string, status string) {
+	mock.ExpectPrepare(findWithOwnerSQL).
+		ExpectQuery().
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows(ownerExpenseRows).
+			AddRow(ownerID, "1", "taxi", 250, "THB", "client visit", `{"travel"}`, nil, "2022-01-02", testTime, testTime, status, 1))
+}
+
+func TestSubmitExpense(t *testing.T) {
+	c, rec := workflowRequest(testUser, "submit", "")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expectOwnedExpense(mock, "7", models.ExpenseStatusDraft)
+	expectActor(mock, testUser)
+	mock.ExpectPrepare(transitionSQL).
+		ExpectQuery().
+		WithArgs("1", "draft", "submitted", "7", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "to_char", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "taxi", 250, "THB", "client visit", `{"travel"}`, nil, "2022-01-02", testTime, testTime, "submitted", 2))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Submit(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status":"submitted"`)
+		assert.Equal(t, `"2"`, rec.Header().Get(headerETag))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubmitExpenseOfAnotherOwnerIsHidden(t *testing.T) {
+	c, rec := workflowRequest(testUser, "submit", "")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expectOwnedExpense(mock, "9", models.ExpenseStatusDraft)
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Submit(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, `{"message":"expense not found"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApproveOwnExpense(t *testing.T) {
+	admin := models.User{ID: "7", Name: "admin", Roles: []string{auth.RoleAdmin}}
+	c, rec := workflowRequest(admin, "approve", "")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expectOwnedExpense(mock, "7", models.ExpenseStatusSubmitted)
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Approve(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, `{"message":"can't approve your own expense"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApproveDraftExpense(t *testing.T) {
+	c, rec := workflowRequest(approverUser, "approve", "")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expectOwnedExpense(mock, "7", models.ExpenseStatusDraft)
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Approve(c)) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, `{"message":"can't approve an expense that is draft"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRejectExpense(t *testing.T) {
+	t.Run("comment is required", func(t *testing.T) {
+		c, rec := workflowRequest(approverUser, "reject", `{"comment":"  "}`)
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+		}
+		expectOwnedExpense(mock, "7", models.ExpenseStatusSubmitted)
+
+		expenseController := setupTest(db)
+
+		if assert.NoError(t, expenseController.Reject(c)) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, `{"message":"comment is required to reject an expense"}`, strings.TrimSpace(rec.Body.String()))
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("changed by another request", func(t *testing.T) {
+		c, rec := workflowRequest(approverUser, "reject", `{"comment":"missing receipt"}`)
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+		}
+		expectOwnedExpense(mock, "7", models.ExpenseStatusSubmitted)
+		expectActor(mock, approverUser)
+		mock.ExpectPrepare(transitionSQL).
+			ExpectQuery().
+			WithArgs("1", "submitted", "rejected", "8", "missing receipt").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		expenseController := setupTest(db)
+
+		if assert.NoError(t, expenseController.Reject(c)) {
+			assert.Equal(t, http.StatusConflict, rec.Code)
+			assert.Equal(t, `{"message":"expense was changed by another request"}`, strings.TrimSpace(rec.Body.String()))
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestExpenseTransitions(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, approverUser)
+	c.SetPath("/expenses/:id/transitions")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	expectOwnedExpense(mock, "7", models.ExpenseStatusRejected)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM expense_transitions t LEFT JOIN users u ON u.id = t.actor_id`)).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows(transitionColumns).
+			AddRow("1", "1", "draft", "submitted", "7", "tester", "", testTime).
+			AddRow("2", "1", "submitted", "rejected", "8", "approver", "missing receipt", testTime))
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Transitions(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `[{"id":"1","expense_id":"1","from":"draft","to":"submitted","actor_id":"7","actor_name":"tester","comment":"","created_at":"2022-01-02T03:04:05Z"},{"id":"2","expense_id":"1","from":"submitted","to":"rejected","actor_id":"8","actor_name":"approver","comment":"missing receipt","created_at":"2022-01-02T03:04:05Z"}]`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
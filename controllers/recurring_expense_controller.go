@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// RecurringExpenseController is a struct for recurring expense controller
+type RecurringExpenseController struct {
+	recurringExpenseService services.RecurringExpenseService
+}
+
+// NewRecurringExpenseController is a function to create new recurring expense controller whose requests share the connection pool of db
+func NewRecurringExpenseController(db *sql.DB) *RecurringExpenseController {
+	return &RecurringExpenseController{
+		recurringExpenseService: *services.NewRecurringExpenseService(*repositories.NewRecurringExpenseRepository(db), *repositories.NewCategoryRepository(db)),
+	}
+}
+
+// GET /recurring-expenses
+// Index is a function to get all recurring expenses
+func (c *RecurringExpenseController) Index(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	recurringExpenses, status, err := c.recurringExpenseService.Gets(e.Request().Context(), ownerID)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, recurringExpenses)
+}
+
+// GET /recurring-expenses/:id
+// Show is a function to get a recurring expense by id
+func (c *RecurringExpenseController) Show(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	recurringExpense, status, err := c.recurringExpenseService.GetById(e.Request().Context(), ownerID, id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, recurringExpense)
+}
+
+// POST /recurring-expenses
+// Store is a function to create a new recurring expense
+func (c *RecurringExpenseController) Store(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	var recurringExpenseRequest types.RecurringExpenseRequest
+
+	if err := bindAndValidateRequest(e, &recurringExpenseRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	recurringExpense, status, err := c.recurringExpenseService.Create(e.Request().Context(), ownerID, recurringExpenseRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusCreated, recurringExpense)
+}
+
+// PUT /recurring-expenses/:id
+// Update is a function to update a recurring expense by id
+func (c *RecurringExpenseController) Update(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	var recurringExpenseRequest types.RecurringExpenseRequest
+
+	if err := bindAndValidateRequest(e, &recurringExpenseRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	recurringExpense, status, err := c.recurringExpenseService.UpdateById(e.Request().Context(), ownerID, id, recurringExpenseRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, recurringExpense)
+}
+
+// DELETE /recurring-expenses/:id
+// Delete is a function to delete a recurring expense by id, the expenses it created are kept
+func (c *RecurringExpenseController) Delete(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	if status, err := c.recurringExpenseService.DeleteById(e.Request().Context(), ownerID, id); err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// GET /recurring-expenses/:id/preview
+// Preview is a function to list the next occurrence dates of a recurring expense
+func (c *RecurringExpenseController) Preview(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	var query types.RecurringExpensePreviewQuery
+
+	if err := bindAndValidateRequest(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	preview, status, err := c.recurringExpenseService.Preview(e.Request().Context(), ownerID, id, query.Count)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, preview)
+}
+
+// POST /recurring-expenses/:id/pause
+// Pause is a function to stop a recurring expense from creating expenses
+func (c *RecurringExpenseController) Pause(e echo.Context) error {
+	return c.setPaused(e, true)
+}
+
+// POST /recurring-expenses/:id/resume
+// Resume is a function to let a paused recurring expense create expenses again
+func (c *RecurringExpenseController) Resume(e echo.Context) error {
+	return c.setPaused(e, false)
+}
+
+// setPaused is a function to pause or resume a recurring expense by id
+func (c *RecurringExpenseController) setPaused(e echo.Context, paused bool) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	id := e.Param("id")
+
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter id"})
+	}
+
+	apply := c.recurringExpenseService.Resume
+	if paused {
+		apply = c.recurringExpenseService.Pause
+	}
+
+	recurringExpense, status, err := apply(e.Request().Context(), ownerID, id)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, recurringExpense)
+}
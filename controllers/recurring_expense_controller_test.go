@@ -0,0 +1,161 @@
+//go:build unit
+
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+var recurringExpenseColumns = []string{"id", "title", "amount", "currency", "note", "tags", "category_id", "frequency", "interval_count",
+	"starts_on", "ends_on", "max_count", "position", "next_on", "paused", "created_at", "updated_at"}
+
+func setupRecurringExpenseTest(db *sql.DB) *RecurringExpenseController {
+	recurringExpenseService := services.NewRecurringExpenseService(*repositories.NewRecurringExpenseRepository(db), *repositories.NewCategoryRepository(db))
+
+	return &RecurringExpenseController{recurringExpenseService: *recurringExpenseService}
+}
+
+func recurringExpenseContext(method string, target string, body string, id string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if id != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+	}
+	auth.SetUser(c, testUser)
+	return c, rec
+}
+
+func TestCreateRecurringExpense(t *testing.T) {
+	c, rec := recurringExpenseContext(http.MethodPost, "/recurring-expenses",
+		`{"title":"rent","amount":12000,"note":"condo","tags":["Housing"],"frequency":"monthly","starts_on":"2022-01-31","count":12}`, "")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO recurring_expenses`)).
+		WithArgs("7", "rent", "12000", "THB", "condo", `{"housing"}`, nil, "monthly", 1, "2022-01-31", nil, 12, 0, "2022-01-31").
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+			AddRow("1", "rent", 12000, "THB", "condo", `{"housing"}`, nil, "monthly", 1, "2022-01-31", nil, 12, 0, "2022-01-31", false, testTime, testTime))
+
+	recurringExpenseController := setupRecurringExpenseTest(db)
+
+	if assert.NoError(t, recurringExpenseController.Store(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"id":"1","title":"rent","amount":12000,"currency":"THB","note":"condo","tags":["housing"],"frequency":"monthly","interval":1,`+
+			`"starts_on":"2022-01-31","ends_on":null,"count":12,"next_on":"2022-01-31","paused":false,"created_at":"2022-01-02T03:04:05Z","updated_at":"2022-01-02T03:04:05Z"}`,
+			strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateRecurringExpenseInvalid(t *testing.T) {
+	subtests := map[string]string{
+		"unknown frequency": `{"title":"rent","amount":12000,"note":"condo","tags":["housing"],"frequency":"hourly","starts_on":"2022-01-31"}`,
+		"ends before start": `{"title":"rent","amount":12000,"note":"condo","tags":["housing"],"frequency":"monthly","starts_on":"2022-01-31","ends_on":"2022-01-01"}`,
+		"zero count":        `{"title":"rent","amount":12000,"note":"condo","tags":["housing"],"frequency":"monthly","starts_on":"2022-01-31","count":0}`,
+	}
+	for name, body := range subtests {
+		t.Run(name, func(t *testing.T) {
+			c, rec := recurringExpenseContext(http.MethodPost, "/recurring-expenses", body, "")
+
+			if assert.NoError(t, setupRecurringExpenseTest(nil).Store(c)) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPreviewRecurringExpense(t *testing.T) {
+	c, rec := recurringExpenseContext(http.MethodGet, "/recurring-expenses/1/preview?count=4", "", "1")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM recurring_expenses WHERE id = $1 AND owner_id = $2`)).
+		WithArgs("1", "7").
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+			AddRow("1", "rent", 12000, "THB", "condo", `{"housing"}`, nil, "monthly", 1, "2022-01-31", "2022-05-01", nil, 1, "2022-02-28", false, testTime, testTime))
+
+	recurringExpenseController := setupRecurringExpenseTest(db)
+
+	if assert.NoError(t, recurringExpenseController.Preview(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"dates":["2022-02-28","2022-03-31","2022-04-30"]}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPauseAndResumeRecurringExpense(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	recurringExpenseController := setupRecurringExpenseTest(db)
+
+	t.Run("pause", func(t *testing.T) {
+		c, rec := recurringExpenseContext(http.MethodPost, "/recurring-expenses/1/pause", "", "1")
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM recurring_expenses WHERE id = $1 AND owner_id = $2`)).
+			WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+				AddRow("1", "gym", 900, "THB", "monthly fee", `{"health"}`, nil, "weekly", 1, "2022-01-03", nil, nil, 3, "2022-01-24", false, testTime, testTime))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE recurring_expenses SET paused = $3, position = $4, next_on = $5, updated_at = NOW()`)).
+			WithArgs("1", "7", true, 3, "2022-01-24").
+			WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+				AddRow("1", "gym", 900, "THB", "monthly fee", `{"health"}`, nil, "weekly", 1, "2022-01-03", nil, nil, 3, "2022-01-24", true, testTime, testTime))
+
+		if assert.NoError(t, recurringExpenseController.Pause(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"paused":true`)
+		}
+	})
+
+	t.Run("resume skips missed occurrences", func(t *testing.T) {
+		c, rec := recurringExpenseContext(http.MethodPost, "/recurring-expenses/1/resume", "", "1")
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM recurring_expenses WHERE id = $1 AND owner_id = $2`)).
+			WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+				AddRow("1", "gym", 900, "THB", "monthly fee", `{"health"}`, nil, "weekly", 1, "2022-01-03", nil, nil, 3, "2022-01-24", true, testTime, testTime))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE recurring_expenses SET paused = $3`)).
+			WithArgs("1", "7", false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+				AddRow("1", "gym", 900, "THB", "monthly fee", `{"health"}`, nil, "weekly", 1, "2022-01-03", nil, nil, 200, "2025-11-03", false, testTime, testTime))
+
+		if assert.NoError(t, recurringExpenseController.Resume(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"paused":false`)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		c, rec := recurringExpenseContext(http.MethodPost, "/recurring-expenses/9/pause", "", "9")
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM recurring_expenses WHERE id = $1 AND owner_id = $2`)).
+			WillReturnError(sql.ErrNoRows)
+
+		if assert.NoError(t, recurringExpenseController.Pause(c)) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// ReportController is a struct for report controller
+type ReportController struct {
+	reportService services.ReportService
+}
+
+// NewReportController is a function to create new report controller whose requests share the connection pool of db
+func NewReportController(db *sql.DB) *ReportController {
+	return &ReportController{
+		reportService: *services.NewReportService(*repositories.NewReportRepository(db)),
+	}
+}
+
+// GET /reports
+// Index is a function to get expense totals, counts, averages and min/max grouped by a dimension
+func (c *ReportController) Index(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	var query types.ReportQuery
+
+	if err := bindAndValidateRequest(e, &query); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	report, status, err := c.reportService.Summarize(e.Request().Context(), ownerID, query)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, report)
+}
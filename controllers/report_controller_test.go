@@ -0,0 +1,109 @@
+//go:build unit
+
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+func setupReportTest(db *sql.DB) *ReportController {
+	reportRepository := repositories.NewReportRepository(db)
+	reportService := services.NewReportService(*reportRepository)
+
+	return &ReportController{
+		reportService: *reportService,
+	}
+}
+
+func TestGetReportByTag(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/reports?group_by=tag&from=2022-01-01&to=2022-01-31&tag=Food", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM expenses e CROSS JOIN UNNEST(e.tags) AS tag
+	WHERE e.owner_id = $1 AND e.deleted_at IS NULL AND e.tags && $2 AND e.spent_at >= $3::date AND e.spent_at <= $4::date
+	GROUP BY 1, 2, 3 ORDER BY 1, 3`)).
+		WithArgs("7", `{"food"}`, "2022-01-01", "2022-01-31").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "label", "currency", "count", "sum", "avg", "min", "max"}).
+			AddRow("food", "", "THB", 2, 150.0, 75.0, 71.0, 79.0))
+
+	reportController := setupReportTest(db)
+
+	if assert.NoError(t, reportController.Index(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"group_by":"tag","from":"2022-01-01","to":"2022-01-31","groups":[{"key":"food","currency":"THB","count":2,"total":150,"average":75,"min":71,"max":79}]}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReportTimeout(t *testing.T) {
+	t.Setenv("QUERY_TIMEOUT_READ", "10ms")
+
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodGet, "/reports?group_by=tag", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM expenses e CROSS JOIN UNNEST(e.tags) AS tag`)).
+		WithArgs("7").
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "label", "currency", "count", "sum", "avg", "min", "max"}))
+
+	reportController := setupReportTest(db)
+
+	if assert.NoError(t, reportController.Index(c)) {
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+		assert.Equal(t, `{"message":"query timed out"}`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestGetReportInvalidRange(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/reports?group_by=month&from=2022-02-01&to=2022-01-01", nil), rec)
+	auth.SetUser(c, testUser)
+
+	reportController := setupReportTest(nil)
+
+	if assert.NoError(t, reportController.Index(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/reports?group_by=year", nil), rec)
+	auth.SetUser(c, testUser)
+
+	if assert.NoError(t, reportController.Index(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
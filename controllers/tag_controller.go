@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// TagController is a struct for tag controller
+type TagController struct {
+	tagService services.TagService
+}
+
+// NewTagController is a function to create new tag controller whose requests share the connection pool of db
+func NewTagController(db *sql.DB) *TagController {
+	return &TagController{
+		tagService: *services.NewTagService(*repositories.NewTagRepository(db)),
+	}
+}
+
+// GET /tags
+// Index is a function to get all tags with usage counts
+func (c *TagController) Index(e echo.Context) error {
+	ownerID, ok := currentUserID(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	tags, err := c.tagService.Gets(e.Request().Context(), ownerID)
+
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, tags)
+}
+
+// PUT /tags/:name
+// Rename is a function to rename a tag across all expenses
+func (c *TagController) Rename(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	name, err := url.PathUnescape(e.Param("name"))
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: "invalid parameter name"})
+	}
+
+	var renameRequest types.TagRenameRequest
+
+	if err := bindAndValidateRequest(e, &renameRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	change, status, err := c.tagService.WithActor(actor).Rename(e.Request().Context(), actor.ID, name, renameRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, change)
+}
+
+// POST /tags/merge
+// Merge is a function to fold several tags into one across all expenses
+func (c *TagController) Merge(e echo.Context) error {
+	actor, ok := currentActor(e)
+	if !ok {
+		return e.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	}
+
+	var mergeRequest types.TagMergeRequest
+
+	if err := bindAndValidateRequest(e, &mergeRequest); err != nil {
+		return e.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+	}
+
+	change, status, err := c.tagService.WithActor(actor).Merge(e.Request().Context(), actor.ID, mergeRequest)
+
+	if err != nil {
+		return e.JSON(status, types.Error{Message: err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, change)
+}
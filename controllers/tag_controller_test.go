@@ -0,0 +1,181 @@
+//go:build unit
+
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+func setupTagTest(db *sql.DB) *TagController {
+	tagRepository := repositories.NewTagRepository(db)
+	tagService := services.NewTagService(*tagRepository)
+
+	return &TagController{
+		tagService: *tagService,
+	}
+}
+
+func TestGetTags(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/tags", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT tag, COUNT(*) FROM expenses, UNNEST(tags) AS tag`)).
+		WithArgs("7").
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "count"}).AddRow("food", 3).AddRow("beverage", 1))
+
+	tagController := setupTagTest(db)
+
+	if assert.NoError(t, tagController.Index(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `[{"name":"food","count":3},{"name":"beverage","count":1}]`, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPut, "/tags/foods", strings.NewReader(`{"name":"  Food "}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/tags/:name")
+	c.SetParamNames("name")
+	c.SetParamValues("foods")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE expenses SET tags = ARRAY(`)+`.*AND status = 'draft' AND deleted_at IS NULL`).
+		WithArgs("7", `{"foods"}`, "food").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	tagController := setupTagTest(db)
+
+	if assert.NoError(t, tagController.Rename(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"name":"food","updated":2}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeTagsNotFound(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPost, "/tags/merge", strings.NewReader(`{"sources":["Drinks","drink"],"target":"beverage"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE expenses SET tags = ARRAY(`)).
+		WithArgs("7", `{"drinks","drink"}`, "beverage").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM expenses WHERE owner_id = $1 AND tags && $2 AND deleted_at IS NULL)`)).
+		WithArgs("7", `{"drinks","drink"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	tagController := setupTagTest(db)
+
+	if assert.NoError(t, tagController.Merge(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRenameTagOnlyOnLockedExpenses(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name":"travel"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+	c.SetPath("/tags/:name")
+	c.SetParamNames("name")
+	c.SetParamValues("taxi")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE expenses SET tags = ARRAY(`)).
+		WithArgs("7", `{"taxi"}`, "travel").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM expenses WHERE owner_id = $1 AND tags && $2 AND deleted_at IS NULL)`)).
+		WithArgs("7", `{"taxi"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	tagController := setupTagTest(db)
+
+	if assert.NoError(t, tagController.Rename(c)) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, `{"message":"tag is used only on expenses locked by the approval workflow"}`, strings.TrimSpace(rec.Body.String()))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateExpenseNormalizesTags(t *testing.T) {
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	body := `{"title":"strawberry smoothie","amount":79,"note":"night market","tags":[" Food ","FOOD","Night  Market"]}`
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, testUser)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectActor(mock, testUser)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO expenses`)).
+		WithArgs("7", "strawberry smoothie", "79", "THB", "night market", `{"food","night market"}`, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status", "version"}).
+			AddRow("1", "strawberry smoothie", 79.0, "THB", "night market", `{"food","night market"}`, nil, "2022-01-02", testTime, testTime, "draft", 1))
+	mock.ExpectCommit()
+
+	expenseController := setupTest(db)
+
+	if assert.NoError(t, expenseController.Store(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
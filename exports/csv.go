@@ -0,0 +1,45 @@
+package exports
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/walkmanrd/assessment/models"
+)
+
+// CSVEncoder is a struct for writing expenses as CSV rows under a header row
+type CSVEncoder struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+// NewCSVEncoder is a function to create new CSV encoder
+func NewCSVEncoder(w io.Writer) Encoder {
+	return &CSVEncoder{writer: csv.NewWriter(w)}
+}
+
+// Encode is a function to write an expense as a CSV row
+func (e *CSVEncoder) Encode(expense models.Expense) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.writer.Write(record(expense))
+}
+
+// Close is a function to flush buffered rows, an empty export still has a header row
+func (e *CSVEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// writeHeader is a function to write the header row once
+func (e *CSVEncoder) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	return e.writer.Write(header)
+}
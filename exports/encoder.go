@@ -0,0 +1,77 @@
+package exports
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/walkmanrd/assessment/models"
+)
+
+// ErrUnknownFormat is an error for an export format without an encoder
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Encoder is an interface for writing expenses one at a time in an export format
+type Encoder interface {
+	Encode(expense models.Expense) error
+	Close() error
+}
+
+// format is a struct for the content type, file extension and encoder of an export format
+type format struct {
+	contentType string
+	extension   string
+	newEncoder  func(w io.Writer) Encoder
+}
+
+// formats is a list of supported export formats
+var formats = map[string]format{
+	"csv":    {contentType: "text/csv; charset=utf-8", extension: "csv", newEncoder: NewCSVEncoder},
+	"ndjson": {contentType: "application/x-ndjson", extension: "ndjson", newEncoder: NewNDJSONEncoder},
+	"xlsx":   {contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", extension: "xlsx", newEncoder: NewXLSXEncoder},
+}
+
+// header is a list of column names of tabular exports
+var header = []string{"id", "title", "amount", "currency", "note", "tags", "category_id", "spent_at", "created_at", "updated_at", "status"}
+
+// NewEncoder is a function to create an encoder of a format writing to w
+func NewEncoder(name string, w io.Writer) (Encoder, error) {
+	f, ok := formats[name]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	return f.newEncoder(w), nil
+}
+
+// ContentType is a function to get the content type of a format
+func ContentType(name string) string {
+	return formats[name].contentType
+}
+
+// Filename is a function to build a download file name of a format
+func Filename(name string, now time.Time) string {
+	return "expenses-" + now.Format("20060102-150405") + "." + formats[name].extension
+}
+
+// record is a function to flatten an expense into tabular columns
+func record(expense models.Expense) []string {
+	categoryID := ""
+	if expense.CategoryID != nil {
+		categoryID = *expense.CategoryID
+	}
+
+	return []string{
+		expense.ID,
+		expense.Title,
+		expense.Amount.String(),
+		expense.Currency,
+		expense.Note,
+		strings.Join(expense.Tags, "|"),
+		categoryID,
+		expense.SpentAt,
+		expense.CreatedAt.Format(time.RFC3339),
+		expense.UpdatedAt.Format(time.RFC3339),
+		expense.Status,
+	}
+}
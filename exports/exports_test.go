@@ -0,0 +1,98 @@
+//go:build unit
+
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/money"
+)
+
+var exportTime = time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func exportExpense() models.Expense {
+	amount, _ := money.ParseAmount("79.5")
+	return models.Expense{
+		ID:        "1",
+		Title:     `strawberry "smoothie" <large>`,
+		Amount:    amount,
+		Currency:  "THB",
+		Note:      "night market, promotion",
+		Tags:      []string{"food", "beverage"},
+		SpentAt:   "2022-01-02",
+		CreatedAt: exportTime,
+		UpdatedAt: exportTime,
+		Status:    models.ExpenseStatusSubmitted,
+	}
+}
+
+func encode(t *testing.T, format string, expenses ...models.Expense) []byte {
+	var buffer bytes.Buffer
+
+	encoder, err := NewEncoder(format, &buffer)
+	assert.NoError(t, err)
+
+	for _, expense := range expenses {
+		assert.NoError(t, encoder.Encode(expense))
+	}
+	assert.NoError(t, encoder.Close())
+
+	return buffer.Bytes()
+}
+
+func TestCSVEncoder(t *testing.T) {
+	expected := "id,title,amount,currency,note,tags,category_id,spent_at,created_at,updated_at,status\n" +
+		`1,"strawberry ""smoothie"" <large>",79.5,THB,"night market, promotion",food|beverage,,2022-01-02,2022-01-02T03:04:05Z,2022-01-02T03:04:05Z,submitted` + "\n"
+
+	assert.Equal(t, expected, string(encode(t, "csv", exportExpense())))
+	assert.Equal(t, "id,title,amount,currency,note,tags,category_id,spent_at,created_at,updated_at,status\n", string(encode(t, "csv")))
+}
+
+func TestNDJSONEncoder(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(encode(t, "ndjson", exportExpense(), exportExpense()))), "\n")
+
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"amount":79.5`)
+	assert.Contains(t, lines[0], `"spent_at":"2022-01-02"`)
+	assert.Contains(t, lines[0], `"status":"submitted"`)
+}
+
+func TestXLSXEncoder(t *testing.T) {
+	content := encode(t, "xlsx", exportExpense())
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	names := []string{}
+	var sheet string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			r, _ := file.Open()
+			data, _ := io.ReadAll(r)
+			sheet = string(data)
+		}
+	}
+
+	assert.Equal(t, []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"}, names)
+	assert.Contains(t, sheet, "<c><v>79.5</v></c>")
+	assert.Contains(t, sheet, "strawberry &#34;smoothie&#34; &lt;large&gt;")
+	assert.Contains(t, sheet, `<c t="inlineStr"><is><t xml:space="preserve">status</t></is></c></row>`)
+	assert.Contains(t, sheet, `<c t="inlineStr"><is><t xml:space="preserve">submitted</t></is></c></row>`)
+	assert.True(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewEncoder("pdf", io.Discard)
+
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,28 @@
+package exports
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/walkmanrd/assessment/models"
+)
+
+// NDJSONEncoder is a struct for writing expenses as newline delimited JSON
+type NDJSONEncoder struct {
+	encoder *json.Encoder
+}
+
+// NewNDJSONEncoder is a function to create new NDJSON encoder
+func NewNDJSONEncoder(w io.Writer) Encoder {
+	return &NDJSONEncoder{encoder: json.NewEncoder(w)}
+}
+
+// Encode is a function to write an expense as a JSON line
+func (e *NDJSONEncoder) Encode(expense models.Expense) error {
+	return e.encoder.Encode(expense)
+}
+
+// Close is a function to finish the export, lines are written as they are encoded
+func (e *NDJSONEncoder) Close() error {
+	return nil
+}
@@ -0,0 +1,149 @@
+package exports
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+
+	"github.com/walkmanrd/assessment/models"
+)
+
+// xlsxParts is a list of the static parts of a single sheet workbook in the order they are zipped
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{
+		name: "[Content_Types].xml",
+		content: xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`,
+	},
+	{
+		name: "_rels/.rels",
+		content: xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`,
+	},
+	{
+		name: "xl/workbook.xml",
+		content: xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Expenses" sheetId="1" r:id="rId1"/></sheets>` +
+			`</workbook>`,
+	},
+	{
+		name: "xl/_rels/workbook.xml.rels",
+		content: xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+	},
+}
+
+const (
+	// xlsxSheetStart is an opening of the worksheet part rows are streamed into
+	xlsxSheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	// xlsxSheetEnd is a closing of the worksheet part
+	xlsxSheetEnd = `</sheetData></worksheet>`
+	// xlsxAmountColumn is an index of the column written as a number
+	xlsxAmountColumn = 2
+)
+
+// XLSXEncoder is a struct for writing expenses as rows of a streamed single sheet workbook
+type XLSXEncoder struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	err   error
+}
+
+// NewXLSXEncoder is a function to create new XLSX encoder
+func NewXLSXEncoder(w io.Writer) Encoder {
+	return &XLSXEncoder{zip: zip.NewWriter(w)}
+}
+
+// Encode is a function to write an expense as a worksheet row
+func (e *XLSXEncoder) Encode(expense models.Expense) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	return e.writeRow(record(expense))
+}
+
+// Close is a function to finish the worksheet and the zip archive
+func (e *XLSXEncoder) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// start is a function to write the static parts and open the worksheet with its header row once
+func (e *XLSXEncoder) start() error {
+	if e.sheet != nil || e.err != nil {
+		return e.err
+	}
+
+	for _, part := range xlsxParts {
+		w, err := e.zip.Create(part.name)
+		if err != nil {
+			e.err = err
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			e.err = err
+			return err
+		}
+	}
+
+	sheet, err := e.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		e.err = err
+		return err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetStart); err != nil {
+		e.err = err
+		return err
+	}
+	e.sheet = sheet
+
+	return e.writeCells(header, -1)
+}
+
+// writeRow is a function to write an expense record with its amount as a number
+func (e *XLSXEncoder) writeRow(cells []string) error {
+	return e.writeCells(cells, xlsxAmountColumn)
+}
+
+// writeCells is a function to write a row of inline string cells and an optional number cell
+func (e *XLSXEncoder) writeCells(cells []string, numberColumn int) error {
+	if _, err := io.WriteString(e.sheet, "<row>"); err != nil {
+		return err
+	}
+
+	for i, cell := range cells {
+		if i == numberColumn {
+			if _, err := io.WriteString(e.sheet, "<c><v>"+cell+"</v></c>"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := io.WriteString(e.sheet, `<c t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(e.sheet, []byte(cell)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(e.sheet, "</t></is></c>"); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(e.sheet, "</row>")
+	return err
+}
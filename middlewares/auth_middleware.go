@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// AuthHeader is a function to build a middleware resolving the authorization header into the current user,
+// users are looked up on db
+func AuthHeader(db *sql.DB) echo.MiddlewareFunc {
+	return authHeader(services.NewUserService(*repositories.NewUserRepository(db), nil))
+}
+
+// authHeader is a function to build the authorization header middleware on a service
+func authHeader(userService *services.UserService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, err := userService.Authenticate(c.Request().Context(), c.Request().Header.Get("Authorization"))
+
+			switch err {
+			case nil:
+				auth.SetUser(c, user)
+				return next(c)
+			case services.ErrUnauthorized:
+				return c.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+			default:
+				return c.JSON(http.StatusInternalServerError, types.Error{Message: err.Error()})
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// Authorize is a middleware to enforce a role policy on the matched route of the current user
+func Authorize(policy auth.Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, ok := auth.CurrentUser(c)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+			}
+
+			if !policy.Allows(user.Roles, c.Request().Method, c.Path()) {
+				return c.JSON(http.StatusForbidden, types.Error{Message: "Forbidden"})
+			}
+
+			return next(c)
+		}
+	}
+}
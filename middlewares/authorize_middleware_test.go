@@ -0,0 +1,51 @@
+//go:build unit
+
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/models"
+)
+
+func TestAuthorize(t *testing.T) {
+	subtests := []struct {
+		name         string
+		user         *models.User
+		method       string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "viewer reads", user: &models.User{ID: "1", Roles: []string{auth.RoleViewer}}, method: http.MethodGet, expectedCode: http.StatusOK, expectedBody: "ok"},
+		{name: "viewer writes", user: &models.User{ID: "1", Roles: []string{auth.RoleViewer}}, method: http.MethodPut, expectedCode: http.StatusForbidden, expectedBody: `{"message":"Forbidden"}`},
+		{name: "editor writes", user: &models.User{ID: "1", Roles: []string{auth.RoleEditor}}, method: http.MethodPut, expectedCode: http.StatusOK, expectedBody: "ok"},
+		{name: "anonymous", method: http.MethodGet, expectedCode: http.StatusUnauthorized, expectedBody: `{"message":"Unauthorized"}`},
+	}
+	for _, subtest := range subtests {
+		t.Run(subtest.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(subtest.method, "/expenses/1", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/expenses/:id")
+			if subtest.user != nil {
+				auth.SetUser(c, *subtest.user)
+			}
+
+			handler := Authorize(auth.DefaultPolicy)(func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			})
+
+			if assert.NoError(t, handler(c)) {
+				assert.Equal(t, subtest.expectedCode, rec.Code)
+				assert.Equal(t, subtest.expectedBody, strings.TrimSpace(rec.Body.String()))
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+)
+
+// BudgetAlerts is a function to build a middleware raising budget threshold alerts for the expense saved by a request,
+// budgets are checked on db
+func BudgetAlerts(db *sql.DB) echo.MiddlewareFunc {
+	return budgetAlerts(services.NewBudgetService(*repositories.NewBudgetRepository(db), *repositories.NewCategoryRepository(db)))
+}
+
+// budgetAlerts is a function to build the budget alert middleware on a service,
+// the saved expense is read back from the response so handlers stay unaware of budgets
+func budgetAlerts(budgetService *services.BudgetService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			err := next(c)
+			c.Response().Writer = recorder.ResponseWriter
+
+			status := c.Response().Status
+			if err != nil || (status != http.StatusOK && status != http.StatusCreated) {
+				return err
+			}
+
+			user, ok := auth.CurrentUser(c)
+			if !ok {
+				return nil
+			}
+
+			var expense models.Expense
+			if json.Unmarshal(recorder.body.Bytes(), &expense) != nil || expense.ID == "" {
+				return nil
+			}
+
+			// the expense is saved already, a failed check must not fail the request
+			if _, err := budgetService.CheckExpense(c.Request().Context(), user.ID, expense); err != nil {
+				c.Logger().Error(err)
+			}
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,117 @@
+//go:build unit
+
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+)
+
+const savedExpense = `{"id":"5","title":"omakase","amount":850,"currency":"THB","note":"birthday","tags":["food"],"spent_at":"2022-03-18"}`
+
+var (
+	budgetColumns      = []string{"id", "name", "amount", "currency", "period", "tag", "category_id", "thresholds", "created_at", "updated_at"}
+	budgetAlertColumns = []string{"id", "budget_id", "period_start", "threshold", "spent", "expense_id", "created_at"}
+)
+
+func budgetAlertRequest(t *testing.T, handler echo.HandlerFunc) (*httptest.ResponseRecorder, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(savedExpense)), rec)
+	auth.SetUser(c, models.User{ID: "7", Roles: []string{auth.RoleEditor}})
+
+	budgetService := services.NewBudgetService(*repositories.NewBudgetRepository(db), *repositories.NewCategoryRepository(db))
+
+	return rec, mock, func() {
+		assert.NoError(t, budgetAlerts(budgetService)(handler)(c))
+	}
+}
+
+func TestBudgetAlerts(t *testing.T) {
+	rec, mock, run := budgetAlertRequest(t, func(c echo.Context) error {
+		return c.JSONBlob(http.StatusCreated, []byte(savedExpense))
+	})
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM budgets
+	WHERE owner_id = $1 AND currency = $2 AND (tag = ANY($3)`)).
+		WithArgs("7", "THB", `{"food"}`, nil).
+		WillReturnRows(sqlmock.NewRows(budgetColumns).AddRow("3", "eating out", 1000, "THB", "monthly", "food", nil, "{50,80,100}", now, now))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(amount), 0), COUNT(*) FROM expenses WHERE owner_id = $1 AND deleted_at IS NULL AND tags && $2 AND currency = $3 AND spent_at >= $4 AND spent_at <= $5`)).
+		WithArgs("7", `{"food"}`, "THB", "2022-03-01", "2022-03-31").
+		WillReturnRows(sqlmock.NewRows([]string{"sum", "count"}).AddRow("920.5", 4))
+	// 50% was reached by an earlier expense this month
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO budget_alerts`)).
+		WithArgs("3", "2022-03-01", 50, "920.5", "5").
+		WillReturnRows(sqlmock.NewRows(budgetAlertColumns))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO budget_alerts`)).
+		WithArgs("3", "2022-03-01", 80, "920.5", "5").
+		WillReturnRows(sqlmock.NewRows(budgetAlertColumns).AddRow("9", "3", "2022-03-01", 80, "920.5", "5", now))
+
+	run()
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, savedExpense, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBudgetAlertsSkipsFailedRequests(t *testing.T) {
+	rec, mock, run := budgetAlertRequest(t, func(c echo.Context) error {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "tags must not be blank"})
+	})
+
+	run()
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBudgetAlertsStopWhenRequestIsCanceled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(savedExpense)).WithContext(ctx), rec)
+	auth.SetUser(c, models.User{ID: "7", Roles: []string{auth.RoleEditor}})
+
+	budgetService := services.NewBudgetService(*repositories.NewBudgetRepository(db), *repositories.NewCategoryRepository(db))
+
+	// the client goes away while the budgets are looked up
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM budgets`)).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows(budgetColumns))
+
+	started := time.Now()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	assert.NoError(t, budgetAlerts(budgetService)(func(c echo.Context) error {
+		return c.JSONBlob(http.StatusCreated, []byte(savedExpense))
+	})(c))
+
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/types"
+)
+
+const (
+	// headerIdempotencyKey is a request header naming a retryable request
+	headerIdempotencyKey = "Idempotency-Key"
+	// headerIdempotentReplayed is a response header marking a replayed response
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	// headerETag is a response header of the entity tag recorded with a response
+	headerETag = "ETag"
+	// maxIdempotencyKeyLength is a maximum length of an Idempotency-Key
+	maxIdempotencyKeyLength = 255
+)
+
+// responseRecorder is a struct to copy a response body while it is written
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write is a function to write a response body and keep a copy
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Idempotency is a function to build a middleware replaying the recorded response of a request retried with
+// the same Idempotency-Key, keys are recorded on db
+func Idempotency(db *sql.DB) echo.MiddlewareFunc {
+	return idempotency(services.NewIdempotencyService(*repositories.NewIdempotencyKeyRepository(db)))
+}
+
+// idempotency is a function to build the idempotency middleware on a service
+func idempotency(idempotencyService *services.IdempotencyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(headerIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return c.JSON(http.StatusBadRequest, types.Error{Message: "Idempotency-Key is too long"})
+			}
+
+			user, ok := auth.CurrentUser(c)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, types.Error{Message: err.Error()})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			record, claimed, err := idempotencyService.Begin(c.Request().Context(), user.ID, key, requestHash(c.Request(), body))
+
+			switch err {
+			case nil:
+			case services.ErrIdempotencyKeyMismatch:
+				return c.JSON(http.StatusUnprocessableEntity, types.Error{Message: err.Error()})
+			case services.ErrIdempotencyKeyInFlight:
+				c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+				return c.JSON(http.StatusConflict, types.Error{Message: err.Error()})
+			default:
+				return c.JSON(http.StatusInternalServerError, types.Error{Message: err.Error()})
+			}
+
+			if !claimed {
+				if record.ETag != "" {
+					c.Response().Header().Set(headerETag, record.ETag)
+				}
+				c.Response().Header().Set(headerIdempotentReplayed, "true")
+				return c.Blob(*record.StatusCode, record.ContentType, record.ResponseBody)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			// the lease is renewed until the handler returns so a slow request isn't claimed again by a retry
+			stopRenewing := idempotencyService.Hold(record)
+
+			// a panicking handler gives the key back before the panic goes on to be recovered
+			defer func() {
+				if r := recover(); r != nil {
+					stopRenewing()
+					if releaseErr := idempotencyService.Release(context.Background(), record); releaseErr != nil {
+						c.Logger().Error(releaseErr)
+					}
+					panic(r)
+				}
+			}()
+
+			err = next(c)
+			stopRenewing()
+			c.Response().Writer = recorder.ResponseWriter
+
+			// failures that may succeed on retry release the key instead of being replayed, so do failures of a
+			// request canceled by its client or by shutdown while a response that made a change is still kept,
+			// the key is released or completed on a context of its own as the request's may be canceled by now
+			status := c.Response().Status
+			canceled := status == services.StatusClientClosedRequest || (c.Request().Context().Err() != nil && status >= http.StatusBadRequest)
+			if err != nil || !c.Response().Committed || status >= http.StatusInternalServerError || canceled {
+				if releaseErr := idempotencyService.Release(context.Background(), record); releaseErr != nil {
+					c.Logger().Error(releaseErr)
+				}
+				return err
+			}
+
+			record.StatusCode = &status
+			record.ContentType = c.Response().Header().Get(echo.HeaderContentType)
+			record.ETag = c.Response().Header().Get(headerETag)
+			record.ResponseBody = recorder.body.Bytes()
+
+			if err := idempotencyService.Complete(context.Background(), record); err != nil {
+				c.Logger().Error(err)
+			}
+
+			return nil
+		}
+	}
+}
+
+// requestHash is a function to fingerprint the method, path and body of a request
+func requestHash(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,249 @@
+//go:build unit
+
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/controllers"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+	"github.com/walkmanrd/assessment/validators"
+)
+
+const idempotencyBody = `{"title":"ramen","amount":120,"note":"lunch","tags":["food"]}`
+
+var (
+	claimSQL       = regexp.QuoteMeta(`INSERT INTO idempotency_keys`)
+	findKeySQL     = regexp.QuoteMeta(`FROM idempotency_keys WHERE owner_id = $1 AND key = $2 AND expires_at > NOW()`)
+	purgeKeysSQL   = regexp.QuoteMeta(`DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	completeKeySQL = regexp.QuoteMeta(`UPDATE idempotency_keys SET status_code = $4`)
+	releaseKeySQL  = regexp.QuoteMeta(`DELETE FROM idempotency_keys WHERE owner_id = $1 AND key = $2`)
+	renewKeySQL    = regexp.QuoteMeta(`UPDATE idempotency_keys SET expires_at = NOW() + $4 * INTERVAL '1 millisecond'`)
+	idempotencyKey = []string{"owner_id", "key", "request_hash", "status_code", "content_type", "etag", "response_body"}
+)
+
+func idempotentRequest(t *testing.T, body string, handler echo.HandlerFunc) (*httptest.ResponseRecorder, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectExec(purgeKeysSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(body))
+	req.Header.Set(headerIdempotencyKey, "retry-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, models.User{ID: "7", Roles: []string{auth.RoleEditor}})
+
+	idempotencyService := services.NewIdempotencyService(*repositories.NewIdempotencyKeyRepository(db))
+
+	return rec, mock, func() {
+		assert.NoError(t, idempotency(idempotencyService)(handler)(c))
+	}
+}
+
+func createdHandler(calls *int) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		*calls++
+		c.Response().Header().Set(headerETag, `"1"`)
+		return c.JSON(http.StatusCreated, map[string]string{"id": "1"})
+	}
+}
+
+func TestIdempotencyFirstRequest(t *testing.T) {
+	calls := 0
+	rec, mock, run := idempotentRequest(t, idempotencyBody, createdHandler(&calls))
+
+	mock.ExpectQuery(claimSQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg(), int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("retry-1"))
+	mock.ExpectExec(completeKeySQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg(), 201, echo.MIMEApplicationJSONCharsetUTF8, `"1"`, []byte(`{"id":"1"}`+"\n"), int64(86400000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	run()
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get(headerIdempotentReplayed))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencySubSecondDurations(t *testing.T) {
+	t.Setenv("IDEMPOTENCY_LEASE", "500ms")
+	t.Setenv("IDEMPOTENCY_TTL", "1500ms")
+	calls := 0
+	rec, mock, run := idempotentRequest(t, idempotencyBody, createdHandler(&calls))
+
+	mock.ExpectQuery(claimSQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg(), int64(500)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("retry-1"))
+	mock.ExpectExec(completeKeySQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg(), 201, echo.MIMEApplicationJSONCharsetUTF8, `"1"`, []byte(`{"id":"1"}`+"\n"), int64(1500)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	run()
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	calls := 0
+	rec, mock, run := idempotentRequest(t, idempotencyBody, createdHandler(&calls))
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/expenses", nil), []byte(idempotencyBody))
+
+	mock.ExpectQuery(claimSQL).WillReturnRows(sqlmock.NewRows([]string{"key"}))
+	mock.ExpectQuery(findKeySQL).
+		WithArgs("7", "retry-1").
+		WillReturnRows(sqlmock.NewRows(idempotencyKey).AddRow("7", "retry-1", hash, 201, echo.MIMEApplicationJSONCharsetUTF8, `"1"`, []byte(`{"id":"1"}`)))
+
+	run()
+
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"id":"1"}`, rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get(headerIdempotentReplayed))
+	assert.Equal(t, `"1"`, rec.Header().Get(headerETag))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyDifferentBody(t *testing.T) {
+	calls := 0
+	rec, mock, run := idempotentRequest(t, `{"title":"sushi"}`, createdHandler(&calls))
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/expenses", nil), []byte(idempotencyBody))
+
+	mock.ExpectQuery(claimSQL).WillReturnRows(sqlmock.NewRows([]string{"key"}))
+	mock.ExpectQuery(findKeySQL).
+		WillReturnRows(sqlmock.NewRows(idempotencyKey).AddRow("7", "retry-1", hash, 201, echo.MIMEApplicationJSONCharsetUTF8, "", []byte(`{"id":"1"}`)))
+
+	run()
+
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	calls := 0
+	rec, mock, run := idempotentRequest(t, idempotencyBody, createdHandler(&calls))
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/expenses", nil), []byte(idempotencyBody))
+
+	mock.ExpectQuery(claimSQL).WillReturnRows(sqlmock.NewRows([]string{"key"}))
+	mock.ExpectQuery(findKeySQL).
+		WillReturnRows(sqlmock.NewRows(idempotencyKey).AddRow("7", "retry-1", hash, nil, "", "", nil))
+
+	run()
+
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get(echo.HeaderRetryAfter))
+}
+
+func TestIdempotencyReleasesOnServerError(t *testing.T) {
+	rec, mock, run := idempotentRequest(t, idempotencyBody, func(c echo.Context) error {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "down"})
+	})
+
+	mock.ExpectQuery(claimSQL).WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("retry-1"))
+	mock.ExpectExec(releaseKeySQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	run()
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyRenewsLeaseOfSlowRequest(t *testing.T) {
+	t.Setenv("IDEMPOTENCY_LEASE", "60ms")
+
+	calls := 0
+	rec, mock, run := idempotentRequest(t, idempotencyBody, func(c echo.Context) error {
+		// the lease is renewed every 20ms, twice before the handler returns
+		time.Sleep(50 * time.Millisecond)
+		return createdHandler(&calls)(c)
+	})
+
+	mock.ExpectQuery(claimSQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg(), int64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("retry-1"))
+	for i := 0; i < 2; i++ {
+		mock.ExpectExec(renewKeySQL).
+			WithArgs("7", "retry-1", sqlmock.AnyArg(), int64(60)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(completeKeySQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg(), 201, echo.MIMEApplicationJSONCharsetUTF8, `"1"`, []byte(`{"id":"1"}`+"\n"), int64(86400000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	run()
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyReleasesOnPanic(t *testing.T) {
+	_, mock, run := idempotentRequest(t, idempotencyBody, func(c echo.Context) error {
+		panic("handler crashed")
+	})
+
+	mock.ExpectQuery(claimSQL).WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("retry-1"))
+	mock.ExpectExec(releaseKeySQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.PanicsWithValue(t, "handler crashed", run)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyReleasesCanceledRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectExec(purgeKeysSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(claimSQL).WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("retry-1"))
+	mock.ExpectExec(releaseKeySQL).
+		WithArgs("7", "retry-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	req := httptest.NewRequest(http.MethodPost, "/expenses", strings.NewReader(idempotencyBody)).WithContext(ctx)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(headerIdempotencyKey, "retry-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	auth.SetUser(c, models.User{ID: "7", Roles: []string{auth.RoleEditor}})
+
+	idempotencyService := services.NewIdempotencyService(*repositories.NewIdempotencyKeyRepository(db))
+	expenseController := controllers.NewExpenseController(db)
+
+	// the client goes away once the key is claimed
+	store := func(c echo.Context) error {
+		cancel()
+		return expenseController.Store(c)
+	}
+
+	assert.NoError(t, idempotency(idempotencyService)(store)(c))
+	assert.Equal(t, services.StatusClientClosedRequest, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
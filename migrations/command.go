@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// usage is a help text of the migrate command
+const usage = "usage: migrate up | down [N] | status | redo"
+
+// Command is a function to run the migrate subcommand with its arguments
+func Command(db *sql.DB, args []string, out io.Writer) error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+	migrator := NewMigrator(db, migrations)
+
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		fmt.Fprintf(out, "applied %d migration(s)\n", applied)
+		return err
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return errors.New(usage)
+			}
+		}
+		reverted, err := migrator.Down(n)
+		fmt.Fprintf(out, "reverted %d migration(s)\n", reverted)
+		return err
+	case "redo":
+		if err := migrator.Redo(); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "redo complete")
+		return nil
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%04d  %-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return errors.New(usage)
+	}
+}
+
+// MigrateUp is a function to apply every pending embedded migration
+func MigrateUp(db *sql.DB) (int, error) {
+	migrations, err := Load()
+	if err != nil {
+		return 0, err
+	}
+	return NewMigrator(db, migrations).Up()
+}
@@ -0,0 +1,252 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// advisoryLockID is a key of the postgres advisory lock held while migrating
+const advisoryLockID = 7305114237
+
+// ErrChecksumMismatch is an error for an applied migration whose file has changed since
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// Status is a struct for the state of a migration
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// appliedMigration is a struct for a row of schema_migrations
+type appliedMigration struct {
+	version   int64
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator is a struct for running migrations against a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator is a function to create new migrator with the given migrations
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}
+}
+
+// Up is a function to apply every pending migration and return how many were applied
+func (m *Migrator) Up() (int, error) {
+	applied := 0
+
+	err := m.locked(func(conn *sql.Conn, done map[int64]appliedMigration) error {
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := apply(conn, migration); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down is a function to revert the last n applied migrations and return how many were reverted
+func (m *Migrator) Down(n int) (int, error) {
+	reverted := 0
+
+	err := m.locked(func(conn *sql.Conn, done map[int64]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0 && reverted < n; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := revert(conn, migration); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Redo is a function to revert and re-apply the last applied migration under one lock,
+// pending migrations are left pending
+func (m *Migrator) Redo() error {
+	return m.locked(func(conn *sql.Conn, done map[int64]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := revert(conn, migration); err != nil {
+				return err
+			}
+			return apply(conn, migration)
+		}
+		return errors.New("no migration to redo")
+	})
+}
+
+// Status is a function to list every migration with the time it was applied
+func (m *Migrator) Status() ([]Status, error) {
+	statuses := []Status{}
+
+	err := m.locked(func(conn *sql.Conn, done map[int64]appliedMigration) error {
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if row, ok := done[migration.Version]; ok {
+				appliedAt := row.appliedAt
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+// locked is a function to run fn on a single connection holding the migration advisory lock
+// after checking that applied migrations still match their files
+func (m *Migrator) locked(fn func(*sql.Conn, map[int64]appliedMigration) error) error {
+	ctx := context.Background()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockID)
+
+	createTable := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	`
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if err := m.verify(done); err != nil {
+		return err
+	}
+
+	return fn(conn, done)
+}
+
+// verify is a function to check applied migrations against known migration files
+func (m *Migrator) verify(done map[int64]appliedMigration) error {
+	known := map[int64]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, row := range done {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("applied migration %d is unknown to this binary", version)
+		}
+		if migration.Checksum != row.checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+// appliedMigrations is a function to read schema_migrations keyed by version
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations ORDER BY version ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int64]appliedMigration{}
+	for rows.Next() {
+		row := appliedMigration{}
+		if err := rows.Scan(&row.version, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		done[row.version] = row
+	}
+
+	return done, rows.Err()
+}
+
+// apply is a function to run the up file of a migration and record it as applied
+func apply(conn *sql.Conn, migration Migration) error {
+	err := inTx(conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, migration.Checksum,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// revert is a function to run the down file of a migration and forget it was applied
+func revert(conn *sql.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+	}
+
+	err := inTx(conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// inTx is a function to run fn in a transaction on a connection
+func inTx(conn *sql.Conn, fn func(*sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
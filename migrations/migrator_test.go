@@ -0,0 +1,168 @@
+//go:build unit
+
+package migrations
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFiles = fstest.MapFS{
+	"0002_add_note.up.sql":       {Data: []byte("ALTER TABLE items ADD COLUMN note TEXT;")},
+	"0002_add_note.down.sql":     {Data: []byte("ALTER TABLE items DROP COLUMN note;")},
+	"0001_create_items.up.sql":   {Data: []byte("CREATE TABLE items (id SERIAL PRIMARY KEY);")},
+	"0001_create_items.down.sql": {Data: []byte("DROP TABLE items;")},
+	"0003_add_index.up.sql":      {Data: []byte("CREATE INDEX items_note_idx ON items (note);")},
+	"0003_add_index.down.sql":    {Data: []byte("DROP INDEX items_note_idx;")},
+}
+
+func loadTestMigrations(t *testing.T) []Migration {
+	migrations, err := LoadFS(testFiles)
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when loading migrations", err)
+	}
+	return migrations
+}
+
+func expectLock(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WithArgs(advisoryLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, checksum, applied_at FROM schema_migrations ORDER BY version ASC")).WillReturnRows(applied)
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+
+	if assert.NoError(t, err) && assert.NotEmpty(t, migrations) {
+		for i, migration := range migrations {
+			assert.Equal(t, int64(i+1), migration.Version, "migrations should be numbered without gaps")
+			assert.NotEmpty(t, migration.Down, "migration %d_%s should have a down file", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	migrations := loadTestMigrations(t)
+
+	if assert.Len(t, migrations, 3) {
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "create_items", migrations[0].Name)
+		assert.Equal(t, "DROP TABLE items;", migrations[0].Down)
+		assert.Equal(t, int64(3), migrations[2].Version)
+		assert.Len(t, migrations[0].Checksum, 64)
+	}
+
+	_, err := LoadFS(fstest.MapFS{"0001_create_items.sql": {Data: []byte("SELECT 1;")}})
+	assert.Error(t, err, "unknown file names should be rejected")
+
+	_, err = LoadFS(fstest.MapFS{"0001_create_items.down.sql": {Data: []byte("SELECT 1;")}})
+	assert.Error(t, err, "migrations without an up file should be rejected")
+}
+
+func TestUp(t *testing.T) {
+	migrations := loadTestMigrations(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLock(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(1, migrations[0].Checksum, time.Now()))
+	for _, migration := range migrations[1:] {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(migration.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)")).
+			WithArgs(migration.Version, migration.Name, migration.Checksum).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(advisoryLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := NewMigrator(db, migrations).Up()
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestUpChecksumMismatch(t *testing.T) {
+	migrations := loadTestMigrations(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLock(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(1, "edited", time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(advisoryLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := NewMigrator(db, migrations).Up()
+
+	assert.Equal(t, 0, applied)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected error (%v), got error (%v)", ErrChecksumMismatch, err)
+	}
+}
+
+func TestDown(t *testing.T) {
+	migrations := loadTestMigrations(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLock(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(1, migrations[0].Checksum, time.Now()).
+		AddRow(2, migrations[1].Checksum, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(migrations[1].Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = $1")).
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(advisoryLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	reverted, err := NewMigrator(db, migrations).Down(1)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, reverted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestRedoLeavesPendingMigrations(t *testing.T) {
+	migrations := loadTestMigrations(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	expectLock(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(1, migrations[0].Checksum, time.Now()).
+		AddRow(2, migrations[1].Checksum, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(migrations[1].Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = $1")).
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(migrations[1].Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)")).
+		WithArgs(migrations[1].Version, migrations[1].Name, migrations[1].Checksum).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(advisoryLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// migration 3 is pending and must not be applied by the redo
+	if assert.NoError(t, NewMigrator(db, migrations).Redo()) {
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+}
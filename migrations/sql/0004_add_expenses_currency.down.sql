@@ -0,0 +1,2 @@
+ALTER TABLE expenses DROP COLUMN IF EXISTS currency;
+ALTER TABLE expenses ALTER COLUMN amount TYPE FLOAT USING amount::FLOAT;
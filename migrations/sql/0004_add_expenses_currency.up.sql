@@ -0,0 +1,2 @@
+ALTER TABLE expenses ALTER COLUMN amount TYPE NUMERIC(19, 4) USING ROUND(amount::NUMERIC, 4);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'THB';
@@ -0,0 +1,2 @@
+ALTER TABLE expenses DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS users;
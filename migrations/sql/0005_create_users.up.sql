@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	api_key_hash TEXT UNIQUE,
+	is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the legacy user owns every expense created before users existed and is resolved from AUTH_TOKEN
+INSERT INTO users (id, name) VALUES (1, 'legacy') ON CONFLICT (id) DO NOTHING;
+SELECT setval('users_id_seq', GREATEST((SELECT MAX(id) FROM users), 1));
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES users (id);
+UPDATE expenses SET owner_id = 1 WHERE owner_id IS NULL;
+ALTER TABLE expenses ALTER COLUMN owner_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS expenses_owner_id_idx ON expenses (owner_id);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET is_admin = 'admin' = ANY(roles);
+ALTER TABLE users DROP COLUMN IF EXISTS roles;
+ALTER TABLE users DROP COLUMN IF EXISTS subject;
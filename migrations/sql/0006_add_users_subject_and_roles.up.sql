@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS subject TEXT UNIQUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{editor}';
+UPDATE users SET roles = '{admin}' WHERE is_admin;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
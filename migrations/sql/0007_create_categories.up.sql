@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS categories (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	parent_id INTEGER REFERENCES categories (id) ON DELETE RESTRICT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_id_name_idx ON categories (COALESCE(parent_id, 0), LOWER(name));
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS expenses_category_id_idx ON expenses (category_id);
+
+-- seed top level categories for the tags we already use
+INSERT INTO categories (name)
+SELECT name FROM (VALUES
+	('Food'), ('Beverage'), ('Groceries'), ('Transport'), ('Housing'), ('Utilities'),
+	('Health'), ('Shopping'), ('Entertainment'), ('Travel'), ('Education')
+) AS seed (name)
+ON CONFLICT DO NOTHING;
+
+-- map each uncategorized expense to the first of its tags that matches a category name
+UPDATE expenses e SET category_id = (
+	SELECT c.id
+	FROM UNNEST(e.tags) WITH ORDINALITY AS t (tag, position)
+	JOIN categories c ON LOWER(c.name) = LOWER(TRIM(t.tag)) OR LOWER(c.name) || 's' = LOWER(TRIM(t.tag))
+	ORDER BY t.position, c.parent_id NULLS FIRST
+	LIMIT 1
+)
+WHERE e.category_id IS NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS expenses_tags_idx;
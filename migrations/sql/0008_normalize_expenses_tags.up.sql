@@ -0,0 +1,13 @@
+UPDATE expenses SET tags = ARRAY(
+	SELECT normalized.tag
+	FROM (
+		SELECT LOWER(REGEXP_REPLACE(TRIM(u.tag), '\s+', ' ', 'g')) AS tag, MIN(u.position) AS position
+		FROM UNNEST(expenses.tags) WITH ORDINALITY AS u (tag, position)
+		GROUP BY 1
+	) AS normalized
+	WHERE normalized.tag <> ''
+	ORDER BY normalized.position
+)
+WHERE tags IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS expenses_tags_idx ON expenses USING GIN (tags);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS expenses_owner_id_created_at_idx;
+ALTER TABLE expenses DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,2 @@
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+CREATE INDEX IF NOT EXISTS expenses_owner_id_created_at_idx ON expenses (owner_id, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS spent_at DATE NOT NULL DEFAULT CURRENT_DATE;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+UPDATE expenses SET spent_at = created_at::date, updated_at = created_at;
+CREATE INDEX IF NOT EXISTS expenses_owner_id_spent_at_idx ON expenses (owner_id, spent_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS attachments (
+	id SERIAL PRIMARY KEY,
+	expense_id INTEGER NOT NULL REFERENCES expenses (id) ON DELETE CASCADE,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (expense_id, checksum)
+);
+CREATE INDEX IF NOT EXISTS attachments_checksum_idx ON attachments (checksum);
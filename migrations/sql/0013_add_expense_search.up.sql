@@ -0,0 +1,12 @@
+-- array_to_string is only stable, the wrapper is immutable so it can back a generated column
+CREATE OR REPLACE FUNCTION expense_search_vector(title TEXT, note TEXT, tags TEXT[]) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+	SELECT setweight(to_tsvector('english', COALESCE(title, '')), 'A')
+		|| setweight(to_tsvector('english', COALESCE(array_to_string(tags, ' '), '')), 'B')
+		|| setweight(to_tsvector('english', COALESCE(note, '')), 'C')
+$$;
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS search tsvector
+	GENERATED ALWAYS AS (expense_search_vector(title, note, tags)) STORED;
+
+CREATE INDEX IF NOT EXISTS expenses_search_idx ON expenses USING GIN (search);
@@ -0,0 +1,3 @@
+ALTER TABLE expenses DROP CONSTRAINT IF EXISTS expenses_recurring_expense_id_spent_at_key;
+ALTER TABLE expenses DROP COLUMN IF EXISTS recurring_expense_id;
+DROP TABLE IF EXISTS recurring_expenses;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+	id SERIAL PRIMARY KEY,
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	title TEXT NOT NULL,
+	amount NUMERIC(19, 4) NOT NULL,
+	currency CHAR(3) NOT NULL,
+	note TEXT NOT NULL DEFAULT '',
+	tags TEXT[] NOT NULL DEFAULT '{}',
+	category_id INTEGER REFERENCES categories (id) ON DELETE SET NULL,
+	frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+	interval_count INTEGER NOT NULL DEFAULT 1 CHECK (interval_count > 0),
+	starts_on DATE NOT NULL,
+	ends_on DATE,
+	max_count INTEGER CHECK (max_count > 0),
+	-- position is the index of the next occurrence, next_on its date or NULL once the schedule ended
+	position INTEGER NOT NULL DEFAULT 0,
+	next_on DATE,
+	paused BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS recurring_expenses_owner_id_idx ON recurring_expenses (owner_id);
+CREATE INDEX IF NOT EXISTS recurring_expenses_due_idx ON recurring_expenses (next_on) WHERE NOT paused AND next_on IS NOT NULL;
+
+-- an occurrence materializes into at most one expense, whichever replica gets there first
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_expense_id INTEGER REFERENCES recurring_expenses (id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD CONSTRAINT expenses_recurring_expense_id_spent_at_key UNIQUE (recurring_expense_id, spent_at);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS budgets (
+	id SERIAL PRIMARY KEY,
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	amount NUMERIC(19, 4) NOT NULL CHECK (amount > 0),
+	currency CHAR(3) NOT NULL,
+	period TEXT NOT NULL CHECK (period IN ('weekly', 'monthly', 'quarterly', 'yearly')),
+	tag TEXT,
+	category_id INTEGER REFERENCES categories (id) ON DELETE CASCADE,
+	thresholds INTEGER[] NOT NULL DEFAULT '{50,80,100}',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	CHECK ((tag IS NULL) <> (category_id IS NULL))
+);
+CREATE INDEX IF NOT EXISTS budgets_owner_id_idx ON budgets (owner_id);
+
+-- a threshold alerts once per budget period
+CREATE TABLE IF NOT EXISTS budget_alerts (
+	id SERIAL PRIMARY KEY,
+	budget_id INTEGER NOT NULL REFERENCES budgets (id) ON DELETE CASCADE,
+	period_start DATE NOT NULL,
+	threshold INTEGER NOT NULL,
+	spent NUMERIC(19, 4) NOT NULL,
+	expense_id INTEGER REFERENCES expenses (id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (budget_id, period_start, threshold)
+);
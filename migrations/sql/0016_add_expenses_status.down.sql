@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS expense_transitions;
+DROP INDEX IF EXISTS expenses_status_idx;
+ALTER TABLE expenses DROP COLUMN IF EXISTS status;
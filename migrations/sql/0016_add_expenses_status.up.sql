@@ -0,0 +1,14 @@
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft'
+	CHECK (status IN ('draft', 'submitted', 'approved', 'rejected', 'reimbursed'));
+CREATE INDEX IF NOT EXISTS expenses_status_idx ON expenses (status) WHERE status = 'submitted';
+
+CREATE TABLE IF NOT EXISTS expense_transitions (
+	id SERIAL PRIMARY KEY,
+	expense_id INTEGER NOT NULL REFERENCES expenses (id) ON DELETE CASCADE,
+	from_status TEXT NOT NULL,
+	to_status TEXT NOT NULL,
+	actor_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+	comment TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS expense_transitions_expense_id_idx ON expense_transitions (expense_id);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS expenses_audit ON expenses;
+DROP FUNCTION IF EXISTS audit_expense_change();
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS reject_audit_change();
@@ -0,0 +1,84 @@
+-- audit events outlive the expenses and users they mention so neither id is a foreign key
+CREATE TABLE IF NOT EXISTS audit_events (
+	id BIGSERIAL PRIMARY KEY,
+	expense_id INTEGER NOT NULL,
+	owner_id INTEGER,
+	actor_id INTEGER,
+	request_id TEXT,
+	action TEXT NOT NULL,
+	changes JSONB NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS audit_events_expense_id_idx ON audit_events (expense_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events (actor_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+
+-- the actor and request are read from the transaction local settings app.actor_id and app.request_id,
+-- changes maps every changed column to its from and to values
+CREATE OR REPLACE FUNCTION audit_expense_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+	before JSONB;
+	after JSONB;
+	changes JSONB := '{}';
+	field TEXT;
+	event TEXT;
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		before := to_jsonb(OLD) - 'search' - 'updated_at' - 'version';
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		after := to_jsonb(NEW) - 'search' - 'updated_at' - 'version';
+	END IF;
+
+	IF TG_OP = 'INSERT' THEN
+		event := 'create';
+	ELSIF TG_OP = 'DELETE' THEN
+		event := 'purge';
+	ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+		event := 'delete';
+	ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+		event := 'restore';
+	ELSIF OLD.status <> NEW.status THEN
+		event := 'transition';
+	ELSE
+		event := 'update';
+	END IF;
+
+	FOR field IN SELECT jsonb_object_keys(COALESCE(after, before)) LOOP
+		IF COALESCE(before -> field, 'null') IS DISTINCT FROM COALESCE(after -> field, 'null') THEN
+			changes := changes || jsonb_build_object(field, jsonb_build_object('from', before -> field, 'to', after -> field));
+		END IF;
+	END LOOP;
+
+	IF changes = '{}' THEN
+		RETURN NULL;
+	END IF;
+
+	INSERT INTO audit_events (expense_id, owner_id, actor_id, request_id, action, changes)
+	VALUES (
+		COALESCE(NEW.id, OLD.id),
+		COALESCE(NEW.owner_id, OLD.owner_id),
+		NULLIF(current_setting('app.actor_id', true), '')::INTEGER,
+		NULLIF(current_setting('app.request_id', true), ''),
+		event,
+		changes
+	);
+	RETURN NULL;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS expenses_audit ON expenses;
+CREATE TRIGGER expenses_audit AFTER INSERT OR UPDATE OR DELETE ON expenses
+	FOR EACH ROW EXECUTE FUNCTION audit_expense_change();
+
+CREATE OR REPLACE FUNCTION reject_audit_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append only';
+END;
+$$;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_change();
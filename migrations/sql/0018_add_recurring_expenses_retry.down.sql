@@ -0,0 +1,2 @@
+ALTER TABLE recurring_expenses DROP COLUMN IF EXISTS retry_at;
+ALTER TABLE recurring_expenses DROP COLUMN IF EXISTS failures;
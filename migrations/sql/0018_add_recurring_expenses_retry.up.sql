@@ -0,0 +1,3 @@
+-- failures counts the runs in a row a schedule failed to materialize, it isn't claimed again before retry_at
+ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS retry_at TIMESTAMPTZ;
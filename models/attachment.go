@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Attachment is a model for a receipt attached to an expense, its content is stored by checksum
+type Attachment struct {
+	ID          string    `json:"id"`
+	ExpenseID   string    `json:"expense_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent is a model for a recorded change of an expense with who made it, in which request and what changed
+type AuditEvent struct {
+	ID        string          `json:"id"`
+	ExpenseID string          `json:"expense_id"`
+	OwnerID   *string         `json:"owner_id"`
+	ActorID   *string         `json:"actor_id"`
+	ActorName *string         `json:"actor_name"`
+	RequestID *string         `json:"request_id"`
+	Action    string          `json:"action"`
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+}
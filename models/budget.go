@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/money"
+)
+
+// Budget is a model for a spending limit on a tag or a category per period
+type Budget struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	Amount     money.Amount  `json:"amount"`
+	Currency   string        `json:"currency"`
+	Period     string        `json:"period"`
+	Tag        *string       `json:"tag,omitempty"`
+	CategoryID *string       `json:"category_id,omitempty"`
+	Thresholds pq.Int64Array `json:"thresholds"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// BudgetAlert is a model for a budget threshold reached in a period
+type BudgetAlert struct {
+	ID          string       `json:"id"`
+	BudgetID    string       `json:"budget_id"`
+	PeriodStart string       `json:"period_start"`
+	Threshold   int          `json:"threshold"`
+	Spent       money.Amount `json:"spent"`
+	ExpenseID   *string      `json:"expense_id"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
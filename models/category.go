@@ -0,0 +1,8 @@
+package models
+
+// Category is a model for category
+type Category struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
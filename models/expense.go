@@ -1,14 +1,37 @@
 package models
 
 import (
+	"time"
+
 	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/money"
+)
+
+const (
+	// ExpenseStatusDraft is a status of an expense that can still be edited
+	ExpenseStatusDraft = "draft"
+	// ExpenseStatusSubmitted is a status of an expense waiting for approval
+	ExpenseStatusSubmitted = "submitted"
+	// ExpenseStatusApproved is a status of an expense approved for reimbursement
+	ExpenseStatusApproved = "approved"
+	// ExpenseStatusRejected is a status of an expense sent back by an approver
+	ExpenseStatusRejected = "rejected"
+	// ExpenseStatusReimbursed is a status of an expense paid back to its owner
+	ExpenseStatusReimbursed = "reimbursed"
 )
 
 // Expense is a model for expense
 type Expense struct {
-	ID     string         `json:"id"`
-	Title  string         `json:"title"`
-	Amount float64        `json:"amount"`
-	Note   string         `json:"note"`
-	Tags   pq.StringArray `json:"tags"`
+	ID         string         `json:"id"`
+	Title      string         `json:"title"`
+	Amount     money.Amount   `json:"amount"`
+	Currency   string         `json:"currency"`
+	Note       string         `json:"note"`
+	Tags       pq.StringArray `json:"tags"`
+	CategoryID *string        `json:"category_id,omitempty"`
+	SpentAt    string         `json:"spent_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Status     string         `json:"status"`
+	Version    int            `json:"-"`
 }
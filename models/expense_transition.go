@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ExpenseTransition is a model for a status change of an expense with who made it and why
+type ExpenseTransition struct {
+	ID        string    `json:"id"`
+	ExpenseID string    `json:"expense_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	ActorID   *string   `json:"actor_id"`
+	ActorName *string   `json:"actor_name"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
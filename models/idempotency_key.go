@@ -0,0 +1,13 @@
+package models
+
+// IdempotencyKey is a model for a client supplied key and the response recorded for it,
+// a nil status code means the original request is still in flight
+type IdempotencyKey struct {
+	OwnerID      string
+	Key          string
+	RequestHash  string
+	StatusCode   *int
+	ContentType  string
+	ETag         string
+	ResponseBody []byte
+}
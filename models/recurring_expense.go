@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/money"
+)
+
+// RecurringExpense is a model for a schedule materializing expenses
+type RecurringExpense struct {
+	ID         string         `json:"id"`
+	Title      string         `json:"title"`
+	Amount     money.Amount   `json:"amount"`
+	Currency   string         `json:"currency"`
+	Note       string         `json:"note"`
+	Tags       pq.StringArray `json:"tags"`
+	CategoryID *string        `json:"category_id,omitempty"`
+	Frequency  string         `json:"frequency"`
+	Interval   int            `json:"interval"`
+	StartsOn   string         `json:"starts_on"`
+	EndsOn     *string        `json:"ends_on"`
+	Count      *int           `json:"count"`
+	Position   int            `json:"-"`
+	NextOn     *string        `json:"next_on"`
+	Paused     bool           `json:"paused"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "github.com/walkmanrd/assessment/money"
+
+// ReportGroup is a model for aggregated expenses of one group in one currency
+type ReportGroup struct {
+	Key      string       `json:"key"`
+	Label    string       `json:"label,omitempty"`
+	Currency string       `json:"currency"`
+	Count    int          `json:"count"`
+	Total    money.Amount `json:"total"`
+	Average  money.Amount `json:"average"`
+	Min      money.Amount `json:"min"`
+	Max      money.Amount `json:"max"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// Tag is a model for a tag and the number of expenses using it
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "github.com/lib/pq"
+
+// User is a model for user
+type User struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Subject    string         `json:"subject,omitempty"`
+	APIKeyHash string         `json:"-"`
+	Roles      pq.StringArray `json:"roles"`
+}
@@ -0,0 +1,146 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is a number of decimal places an amount keeps, the largest minor unit in ISO 4217
+const Scale = 4
+
+// unit is a number of stored units in one major currency unit
+const unit = 10000
+
+// ErrInvalidAmount is an error for a value that is not an exact decimal amount
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Amount is an exact decimal amount stored as an integer number of 1/10000 units
+type Amount int64
+
+// ParseAmount is a function to parse a decimal string such as "79", "-0.1" or "7.95e1" exactly
+func ParseAmount(value string) (Amount, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return 0, ErrInvalidAmount
+	}
+
+	rat.Mul(rat, big.NewRat(unit, 1))
+	if !rat.IsInt() {
+		return 0, fmt.Errorf("%w: more than %d decimal places", ErrInvalidAmount, Scale)
+	}
+	if !rat.Num().IsInt64() {
+		return 0, fmt.Errorf("%w: out of range", ErrInvalidAmount)
+	}
+
+	return Amount(rat.Num().Int64()), nil
+}
+
+// FromFloat is a function to convert a float to the nearest amount
+func FromFloat(value float64) Amount {
+	return Amount(math.Round(value * unit))
+}
+
+// Float64 is a function to convert an amount to the nearest float
+func (a Amount) Float64() float64 {
+	return float64(a) / unit
+}
+
+// Decimals is a function to count the significant decimal places of an amount
+func (a Amount) Decimals() int {
+	fraction := int64(a) % unit
+	if fraction == 0 {
+		return 0
+	}
+
+	decimals := Scale
+	for fraction%10 == 0 {
+		fraction /= 10
+		decimals--
+	}
+	return decimals
+}
+
+// String is a function to format an amount without trailing zeros
+func (a Amount) String() string {
+	sign := ""
+	value := uint64(a)
+	if a < 0 {
+		sign = "-"
+		value = uint64(-a)
+	}
+
+	whole := strconv.FormatUint(value/unit, 10)
+	fraction := value % unit
+	if fraction == 0 {
+		return sign + whole
+	}
+
+	return sign + whole + "." + strings.TrimRight(fmt.Sprintf("%0*d", Scale, fraction), "0")
+}
+
+// MarshalJSON is a function to write an amount as a JSON number
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON is a function to read an amount from a JSON number or a decimal string
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	if value == "null" {
+		return nil
+	}
+
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	amount, err := ParseAmount(value)
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+	return nil
+}
+
+// UnmarshalText is a function to read an amount from a query parameter
+func (a *Amount) UnmarshalText(text []byte) error {
+	amount, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+	return nil
+}
+
+// Scan is a function to read an amount from a NUMERIC column
+func (a *Amount) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case []byte:
+		return a.UnmarshalText(value)
+	case string:
+		return a.UnmarshalText([]byte(value))
+	case float64:
+		*a = FromFloat(value)
+		return nil
+	case int64:
+		*a = Amount(value * unit)
+		return nil
+	case nil:
+		*a = 0
+		return nil
+	default:
+		return fmt.Errorf("can't scan %T into money.Amount", src)
+	}
+}
+
+// Value is a function to write an amount to a NUMERIC column
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
@@ -0,0 +1,94 @@
+//go:build unit
+
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmount(t *testing.T) {
+	subtests := []struct {
+		value       string
+		expected    Amount
+		expectedErr error
+	}{
+		{value: "79", expected: 790000},
+		{value: "0.1", expected: 1000},
+		{value: "-12.3456", expected: -123456},
+		{value: "7.95e1", expected: 795000},
+		{value: "0.00001", expectedErr: ErrInvalidAmount},
+		{value: "abc", expectedErr: ErrInvalidAmount},
+		{value: "1e30", expectedErr: ErrInvalidAmount},
+	}
+	for _, subtest := range subtests {
+		t.Run(subtest.value, func(t *testing.T) {
+			amount, err := ParseAmount(subtest.value)
+			if !errors.Is(err, subtest.expectedErr) {
+				t.Errorf("expected error (%v), got error (%v)", subtest.expectedErr, err)
+			}
+			assert.Equal(t, subtest.expected, amount)
+		})
+	}
+}
+
+func TestAmountIsExact(t *testing.T) {
+	var total Amount
+	for i := 0; i < 10; i++ {
+		amount, _ := ParseAmount("0.1")
+		total += amount
+	}
+
+	assert.Equal(t, "1", total.String())
+}
+
+func TestAmountString(t *testing.T) {
+	assert.Equal(t, "79", Amount(790000).String())
+	assert.Equal(t, "79.5", Amount(795000).String())
+	assert.Equal(t, "-0.0001", Amount(-1).String())
+	assert.Equal(t, 1, Amount(795000).Decimals())
+	assert.Equal(t, 4, Amount(1).Decimals())
+	assert.Equal(t, 0, Amount(790000).Decimals())
+}
+
+func TestAmountJSON(t *testing.T) {
+	var fromNumber, fromString Amount
+
+	assert.NoError(t, json.Unmarshal([]byte(`79.95`), &fromNumber))
+	assert.NoError(t, json.Unmarshal([]byte(`"79.95"`), &fromString))
+	assert.Equal(t, Amount(799500), fromNumber)
+	assert.Equal(t, fromNumber, fromString)
+
+	encoded, err := json.Marshal(struct {
+		Amount Amount `json:"amount"`
+	}{fromNumber})
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"amount":79.95}`, string(encoded))
+	}
+}
+
+func TestAmountScan(t *testing.T) {
+	var amount Amount
+
+	assert.NoError(t, amount.Scan([]byte("79.9500")))
+	assert.Equal(t, Amount(799500), amount)
+	assert.NoError(t, amount.Scan(0.1))
+	assert.Equal(t, Amount(1000), amount)
+	assert.Error(t, amount.Scan(true))
+}
+
+func TestExponent(t *testing.T) {
+	exponent, ok := Exponent("JPY")
+	assert.True(t, ok)
+	assert.Equal(t, 0, exponent)
+
+	exponent, ok = Exponent("KWD")
+	assert.True(t, ok)
+	assert.Equal(t, 3, exponent)
+
+	assert.True(t, IsCurrency(NormalizeCurrency(" thb ")))
+	assert.False(t, IsCurrency("XXX"))
+}
@@ -0,0 +1,292 @@
+package patches
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is an error for a patch document that can not be parsed
+	ErrInvalidPatch = errors.New("invalid patch document")
+	// ErrPatchConflict is an error for a patch operation that can not be applied to the document
+	ErrPatchConflict = errors.New("patch can not be applied")
+)
+
+// Operation is a struct for a JSON patch operation
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch is a function to apply a JSON patch (RFC 6902) to a document
+func JSONPatch(document []byte, patch []byte) ([]byte, error) {
+	var target interface{}
+	var operations []Operation
+
+	if err := decode(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	for i, operation := range operations {
+		var err error
+		target, err = apply(target, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+// apply is a function to apply a single JSON patch operation
+func apply(document interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, ErrInvalidPatch
+		}
+		var value interface{}
+		if err := decode(operation.Value, &value); err != nil {
+			return nil, ErrInvalidPatch
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(document, path, value)
+		case "replace":
+			if _, err := get(document, path); err != nil {
+				return nil, err
+			}
+			document, _, err = remove(document, path)
+			if err != nil {
+				return nil, err
+			}
+			return add(document, path, value)
+		default:
+			current, err := get(document, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrPatchConflict
+			}
+			return document, nil
+		}
+	case "remove":
+		document, _, err = remove(document, path)
+		return document, err
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(document, from)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "move" {
+			if strings.HasPrefix(operation.Path+"/", operation.From+"/") && operation.Path != operation.From {
+				return nil, ErrPatchConflict
+			}
+			document, _, err = remove(document, from)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			value = clone(value)
+		}
+		return add(document, path, value)
+	default:
+		return nil, ErrInvalidPatch
+	}
+}
+
+// parsePointer is a function to split a JSON pointer (RFC 6901) into tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, ErrInvalidPatch
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// get is a function to read the value at a path
+func get(document interface{}, path []string) (interface{}, error) {
+	current := document
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, ErrPatchConflict
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, ErrPatchConflict
+		}
+	}
+	return current, nil
+}
+
+// add is a function to add a value at a path and return the new document
+func add(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+		return document, nil
+	case []interface{}:
+		index := len(node)
+		if token != "-" {
+			index, err = arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+		}
+		updated := append(node[:index:index], append([]interface{}{value}, node[index:]...)...)
+		return replaceAt(document, path[:len(path)-1], updated)
+	default:
+		return nil, ErrPatchConflict
+	}
+}
+
+// remove is a function to remove the value at a path and return the new document and removed value
+func remove(document interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, document, nil
+	}
+
+	parent, err := get(document, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[token]
+		if !ok {
+			return nil, nil, ErrPatchConflict
+		}
+		delete(node, token)
+		return document, value, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		updated := append(node[:index:index], node[index+1:]...)
+		document, err = replaceAt(document, path[:len(path)-1], updated)
+		return document, value, err
+	default:
+		return nil, nil, ErrPatchConflict
+	}
+}
+
+// replaceAt is a function to swap the container at a path for an updated one
+func replaceAt(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	default:
+		return nil, ErrPatchConflict
+	}
+	return document, nil
+}
+
+// arrayIndex is a function to parse an array index token within bounds
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrPatchConflict
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, ErrPatchConflict
+	}
+	return index, nil
+}
+
+// equal is a function to compare two decoded JSON values
+func equal(a interface{}, b interface{}) bool {
+	if numberA, ok := a.(json.Number); ok {
+		if numberB, ok := b.(json.Number); ok {
+			floatA, errA := numberA.Float64()
+			floatB, errB := numberB.Float64()
+			return errA == nil && errB == nil && floatA == floatB
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// clone is a function to deep copy a decoded JSON value
+func clone(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(node))
+		for key, item := range node {
+			copied[key] = clone(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+		for i, item := range node {
+			copied[i] = clone(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
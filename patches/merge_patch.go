@@ -0,0 +1,50 @@
+package patches
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MergePatch is a function to apply a JSON merge patch (RFC 7396) to a document
+func MergePatch(document []byte, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+
+	if err := decode(document, &target); err != nil {
+		return nil, err
+	}
+	if err := decode(patch, &changes); err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	return json.Marshal(mergeValue(target, changes))
+}
+
+// mergeValue is a function to merge a patch value into a target value
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergeValue(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// decode is a function to decode JSON keeping numbers exact
+func decode(data []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(value)
+}
@@ -0,0 +1,108 @@
+//go:build unit
+
+package patches
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const document = `{"title":"strawberry smoothie","amount":79,"note":"night market","tags":["food","beverage"]}`
+
+func TestMergePatch(t *testing.T) {
+	subtests := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "replace a field",
+			patch:    `{"note":"updated"}`,
+			expected: `{"amount":79,"note":"updated","tags":["food","beverage"],"title":"strawberry smoothie"}`,
+		},
+		{
+			name:     "remove a field with null",
+			patch:    `{"note":null}`,
+			expected: `{"amount":79,"tags":["food","beverage"],"title":"strawberry smoothie"}`,
+		},
+		{
+			name:     "replace an array as a whole",
+			patch:    `{"tags":["drink"]}`,
+			expected: `{"amount":79,"note":"night market","tags":["drink"],"title":"strawberry smoothie"}`,
+		},
+	}
+	for _, subtest := range subtests {
+		t.Run(subtest.name, func(t *testing.T) {
+			patched, err := MergePatch([]byte(document), []byte(subtest.patch))
+			if assert.NoError(t, err) {
+				assert.JSONEq(t, subtest.expected, string(patched))
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	subtests := []struct {
+		name        string
+		patch       string
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "append a tag",
+			patch:    `[{"op":"add","path":"/tags/-","value":"night"}]`,
+			expected: `{"amount":79,"note":"night market","tags":["food","beverage","night"],"title":"strawberry smoothie"}`,
+		},
+		{
+			name:     "insert and remove tags",
+			patch:    `[{"op":"add","path":"/tags/0","value":"snack"},{"op":"remove","path":"/tags/2"}]`,
+			expected: `{"amount":79,"note":"night market","tags":["snack","food"],"title":"strawberry smoothie"}`,
+		},
+		{
+			name:     "test then replace",
+			patch:    `[{"op":"test","path":"/amount","value":79},{"op":"replace","path":"/amount","value":80}]`,
+			expected: `{"amount":80,"note":"night market","tags":["food","beverage"],"title":"strawberry smoothie"}`,
+		},
+		{
+			name:     "copy and move",
+			patch:    `[{"op":"copy","from":"/title","path":"/note"},{"op":"move","from":"/tags/1","path":"/tags/0"}]`,
+			expected: `{"amount":79,"note":"strawberry smoothie","tags":["beverage","food"],"title":"strawberry smoothie"}`,
+		},
+		{
+			name:        "failed test",
+			patch:       `[{"op":"test","path":"/amount","value":1}]`,
+			expectedErr: ErrPatchConflict,
+		},
+		{
+			name:        "remove out of range",
+			patch:       `[{"op":"remove","path":"/tags/5"}]`,
+			expectedErr: ErrPatchConflict,
+		},
+		{
+			name:        "unknown operation",
+			patch:       `[{"op":"merge","path":"/tags"}]`,
+			expectedErr: ErrInvalidPatch,
+		},
+		{
+			name:        "malformed patch",
+			patch:       `{"op":"add"}`,
+			expectedErr: ErrInvalidPatch,
+		},
+	}
+	for _, subtest := range subtests {
+		t.Run(subtest.name, func(t *testing.T) {
+			patched, err := JSONPatch([]byte(document), []byte(subtest.patch))
+			if subtest.expectedErr != nil {
+				if !errors.Is(err, subtest.expectedErr) {
+					t.Errorf("expected error (%v), got error (%v)", subtest.expectedErr, err)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.JSONEq(t, subtest.expected, string(patched))
+			}
+		})
+	}
+}
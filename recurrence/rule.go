@@ -0,0 +1,144 @@
+// Package recurrence computes the dates of RRULE-like schedules, a rule repeats daily, weekly,
+// monthly or yearly every interval periods from its start date until an end date or a count.
+package recurrence
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// Daily is a frequency repeating every interval days
+	Daily = "daily"
+	// Weekly is a frequency repeating every interval weeks
+	Weekly = "weekly"
+	// Monthly is a frequency repeating every interval months on the start day, clamped to short months
+	Monthly = "monthly"
+	// Yearly is a frequency repeating every interval years on the start date, clamped in non leap years
+	Yearly = "yearly"
+)
+
+// DateLayout is a layout of the dates of a rule
+const DateLayout = "2006-01-02"
+
+// ErrInvalidRule is an error for a rule with an unknown frequency or a non positive interval
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// Rule is a struct for a recurrence rule, a zero Until or Count leaves the rule unbounded on that side
+type Rule struct {
+	Frequency string
+	Interval  int
+	Start     time.Time
+	Until     time.Time
+	Count     int
+}
+
+// Validate is a function to check the frequency and interval of a rule
+func (r Rule) Validate() error {
+	switch r.Frequency {
+	case Daily, Weekly, Monthly, Yearly:
+	default:
+		return ErrInvalidRule
+	}
+	if r.Interval < 1 {
+		return ErrInvalidRule
+	}
+	return nil
+}
+
+// At is a function to get the date of the nth occurrence of a rule counting from 0,
+// false is returned when the rule ends before it
+func (r Rule) At(n int) (time.Time, bool) {
+	if n < 0 || (r.Count > 0 && n >= r.Count) {
+		return time.Time{}, false
+	}
+
+	date := r.date(n)
+	if !r.Until.IsZero() && date.After(r.Until) {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// Seek is a function to get the index of the first occurrence of a rule on or after a date,
+// the index may be past the end of the rule
+func (r Rule) Seek(from time.Time) int {
+	from = Truncate(from)
+	start := Truncate(r.Start)
+	if !from.After(start) {
+		return 0
+	}
+
+	var n int
+	switch r.Frequency {
+	case Daily:
+		n = int(from.Sub(start).Hours()/24) / r.Interval
+	case Weekly:
+		n = int(from.Sub(start).Hours()/24) / (7 * r.Interval)
+	case Monthly:
+		n = monthsBetween(start, from) / r.Interval
+	case Yearly:
+		n = (from.Year() - start.Year()) / r.Interval
+	}
+
+	// the estimate is at most one period off either way
+	for n > 0 && !r.date(n-1).Before(from) {
+		n--
+	}
+	for r.date(n).Before(from) {
+		n++
+	}
+	return n
+}
+
+// Next is a function to list up to limit occurrence dates of a rule starting from the nth
+func (r Rule) Next(n int, limit int) []time.Time {
+	dates := []time.Time{}
+	for i := n; len(dates) < limit; i++ {
+		date, ok := r.At(i)
+		if !ok {
+			break
+		}
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// date is a function to get the date of the nth period of a rule ignoring its end
+func (r Rule) date(n int) time.Time {
+	start := Truncate(r.Start)
+	step := n * r.Interval
+
+	switch r.Frequency {
+	case Weekly:
+		return start.AddDate(0, 0, 7*step)
+	case Monthly:
+		return addMonths(start, step)
+	case Yearly:
+		return addMonths(start, 12*step)
+	default:
+		return start.AddDate(0, 0, step)
+	}
+}
+
+// Truncate is a function to get the date of a time as midnight UTC
+func Truncate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// addMonths is a function to add months to a date keeping its day, clamped to the last day of the month
+func addMonths(date time.Time, months int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+
+	day := date.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// monthsBetween is a function to count the calendar months from one date to another
+func monthsBetween(from time.Time, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
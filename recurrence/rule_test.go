@@ -0,0 +1,88 @@
+//go:build unit
+
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(value string) time.Time {
+	parsed, err := time.Parse(DateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func format(dates []time.Time) []string {
+	formatted := []string{}
+	for _, d := range dates {
+		formatted = append(formatted, d.Format(DateLayout))
+	}
+	return formatted
+}
+
+func TestNext(t *testing.T) {
+	subtests := []struct {
+		name     string
+		rule     Rule
+		expected []string
+	}{
+		{
+			name:     "daily every 3 days",
+			rule:     Rule{Frequency: Daily, Interval: 3, Start: date("2022-02-26")},
+			expected: []string{"2022-02-26", "2022-03-01", "2022-03-04", "2022-03-07"},
+		},
+		{
+			name:     "weekly every 2 weeks until",
+			rule:     Rule{Frequency: Weekly, Interval: 2, Start: date("2022-01-03"), Until: date("2022-02-14")},
+			expected: []string{"2022-01-03", "2022-01-17", "2022-01-31", "2022-02-14"},
+		},
+		{
+			name:     "monthly on the 31st",
+			rule:     Rule{Frequency: Monthly, Interval: 1, Start: date("2022-01-31")},
+			expected: []string{"2022-01-31", "2022-02-28", "2022-03-31", "2022-04-30"},
+		},
+		{
+			name:     "yearly on leap day with count",
+			rule:     Rule{Frequency: Yearly, Interval: 1, Start: date("2020-02-29"), Count: 3},
+			expected: []string{"2020-02-29", "2021-02-28", "2022-02-28"},
+		},
+	}
+	for _, subtest := range subtests {
+		t.Run(subtest.name, func(t *testing.T) {
+			assert.Equal(t, subtest.expected, format(subtest.rule.Next(0, 4)))
+		})
+	}
+}
+
+func TestSeek(t *testing.T) {
+	rule := Rule{Frequency: Monthly, Interval: 2, Start: date("2022-01-31")}
+
+	assert.Equal(t, 0, rule.Seek(date("2021-12-01")))
+	assert.Equal(t, 0, rule.Seek(date("2022-01-31")))
+	assert.Equal(t, 1, rule.Seek(date("2022-02-01")))
+	assert.Equal(t, 1, rule.Seek(date("2022-03-31")))
+	assert.Equal(t, 2, rule.Seek(date("2022-04-01")))
+	assert.Equal(t, 6, rule.Seek(date("2022-12-31")))
+
+	for _, frequency := range []string{Daily, Weekly, Monthly, Yearly} {
+		rule := Rule{Frequency: frequency, Interval: 3, Start: date("2020-02-29")}
+		for from := date("2020-01-01"); from.Before(date("2030-01-01")); from = from.AddDate(0, 0, 17) {
+			n := rule.Seek(from)
+			assert.False(t, rule.date(n).Before(from), "%s occurrence %d is before %s", frequency, n, from)
+			if n > 0 {
+				assert.True(t, rule.date(n-1).Before(from), "%s occurrence %d is not before %s", frequency, n-1, from)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Rule{Frequency: Weekly, Interval: 1}.Validate())
+	assert.ErrorIs(t, Rule{Frequency: "hourly", Interval: 1}.Validate(), ErrInvalidRule)
+	assert.ErrorIs(t, Rule{Frequency: Daily}.Validate(), ErrInvalidRule)
+}
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+)
+
+// attachmentColumns is a list of attachment columns in the order scanned by scanAttachment
+const attachmentColumns = "id, expense_id, filename, content_type, size, checksum, created_at"
+
+// scanAttachment is a function to scan attachment columns into a model
+func scanAttachment(row rowScanner) (models.Attachment, error) {
+	attachment := models.Attachment{}
+	err := row.Scan(&attachment.ID, &attachment.ExpenseID, &attachment.Filename, &attachment.ContentType, &attachment.Size, &attachment.Checksum, &attachment.CreatedAt)
+	return attachment, err
+}
+
+// AttachmentRepository is a repository for attachment
+type AttachmentRepository struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// NewAttachmentRepository is a function to create new attachment repository
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{
+		db: db,
+	}
+}
+
+// WithTx is a function to get a copy of the repository running its queries in a transaction
+func (r *AttachmentRepository) WithTx(tx *sql.Tx) AttachmentRepository {
+	return AttachmentRepository{db: r.db, tx: tx}
+}
+
+// conn is a function to get the transaction the repository is bound to or else its database
+func (r *AttachmentRepository) conn() Querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+	return r.db
+}
+
+// WithChecksumLock is a function to run fn with a copy of the repository in a transaction holding a lock on checksum,
+// content is stored or removed under the lock so it isn't removed while another attachment starts to refer to it
+func (r *AttachmentRepository) WithChecksumLock(ctx context.Context, checksum string, fn func(repository AttachmentRepository) error) error {
+	// a repository already in a transaction takes the lock in it
+	if r.tx != nil {
+		if _, err := r.tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", checksum); err != nil {
+			return err
+		}
+		return fn(*r)
+	}
+
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", checksum); err != nil {
+		return err
+	}
+
+	if err := fn(AttachmentRepository{db: r.db, tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindAll is a function to get the attachments of an expense
+func (r *AttachmentRepository) FindAll(ctx context.Context, expenseID string) ([]models.Attachment, error) {
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE expense_id = $1 ORDER BY id ASC", expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+// FindOne is a function to get an attachment of an expense by id
+func (r *AttachmentRepository) FindOne(ctx context.Context, expenseID string, id string) (models.Attachment, error) {
+	return scanAttachment(r.conn().QueryRowContext(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE id = $1 AND expense_id = $2", id, expenseID))
+}
+
+// Create is a function to attach content to an expense, it returns false with the existing attachment
+// when the expense already has content with the same checksum
+func (r *AttachmentRepository) Create(ctx context.Context, attachment models.Attachment) (models.Attachment, bool, error) {
+	sqlCommand := `
+	INSERT INTO attachments (expense_id, filename, content_type, size, checksum) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (expense_id, checksum) DO NOTHING
+	RETURNING ` + attachmentColumns
+
+	created, err := scanAttachment(r.conn().QueryRowContext(ctx, sqlCommand, attachment.ExpenseID, attachment.Filename, attachment.ContentType, attachment.Size, attachment.Checksum))
+	if err != sql.ErrNoRows {
+		return created, err == nil, err
+	}
+
+	existing, err := scanAttachment(r.conn().QueryRowContext(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE expense_id = $1 AND checksum = $2", attachment.ExpenseID, attachment.Checksum))
+	return existing, false, err
+}
+
+// Delete is a function to delete an attachment of an expense by id
+func (r *AttachmentRepository) Delete(ctx context.Context, expenseID string, id string) error {
+	result, err := r.conn().ExecContext(ctx, "DELETE FROM attachments WHERE id = $1 AND expense_id = $2", id, expenseID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(result)
+}
+
+// IsChecksumUsed is a function to check if any attachment still refers to stored content
+func (r *AttachmentRepository) IsChecksumUsed(ctx context.Context, checksum string) (bool, error) {
+	var used bool
+	err := r.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM attachments WHERE checksum = $1)", checksum).Scan(&used)
+
+	return used, err
+}
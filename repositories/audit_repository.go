@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// AuditRepository is a repository for the audit events of expense changes,
+// events are only ever written by the expenses_audit trigger
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository is a function to create new audit repository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+// auditFilter is a function to build the conditions and arguments of audit events matching a query
+func auditFilter(query types.AuditQuery) ([]string, []interface{}) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if query.ExpenseID != "" {
+		conditions = append(conditions, "a.expense_id = "+arg(query.ExpenseID))
+	}
+	if query.OwnerID != "" {
+		conditions = append(conditions, "a.owner_id = "+arg(query.OwnerID))
+	}
+	if query.ActorID != "" {
+		conditions = append(conditions, "a.actor_id = "+arg(query.ActorID))
+	}
+	if query.Action != "" {
+		conditions = append(conditions, "a.action = "+arg(query.Action))
+	}
+	if query.RequestID != "" {
+		conditions = append(conditions, "a.request_id = "+arg(query.RequestID))
+	}
+	if query.From != "" {
+		conditions = append(conditions, "a.created_at >= "+arg(query.From)+"::date")
+	}
+	if query.To != "" {
+		conditions = append(conditions, "a.created_at < "+arg(query.To)+"::date + 1")
+	}
+
+	return conditions, args
+}
+
+// FindAll is a function to get a page of audit events matching a query newest first
+func (r *AuditRepository) FindAll(ctx context.Context, query types.AuditQuery, limit int) ([]models.AuditEvent, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	conditions, args := auditFilter(query)
+	args = append(args, limit, query.Offset)
+	sqlCommand := fmt.Sprintf(`
+	SELECT a.id, a.expense_id, a.owner_id, a.actor_id, u.name, a.request_id, a.action, a.changes, a.created_at
+	FROM audit_events a LEFT JOIN users u ON u.id = a.actor_id
+	WHERE %s
+	ORDER BY a.id DESC LIMIT $%d OFFSET $%d`,
+		strings.Join(conditions, " AND "), len(args)-1, len(args),
+	)
+
+	rows, err := r.db.QueryContext(ctx, sqlCommand, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+
+	for rows.Next() {
+		event := models.AuditEvent{}
+		err := rows.Scan(&event.ID, &event.ExpenseID, &event.OwnerID, &event.ActorID, &event.ActorName, &event.RequestID, &event.Action, &event.Changes, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// Count is a function to count audit events matching a query
+func (r *AuditRepository) Count(ctx context.Context, query types.AuditQuery) (int, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	conditions, args := auditFilter(query)
+
+	var total int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events a WHERE "+strings.Join(conditions, " AND "), args...).Scan(&total)
+	return total, err
+}
@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/money"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// budgetColumns is a list of budget columns in the order scanned by scanBudget
+const budgetColumns = "id, name, amount, currency, period, tag, category_id, thresholds, created_at, updated_at"
+
+// budgetAlertColumns is a list of budget alert columns in the order scanned by scanBudgetAlert
+const budgetAlertColumns = "id, budget_id, TO_CHAR(period_start, 'YYYY-MM-DD'), threshold, spent, expense_id, created_at"
+
+// scanBudget is a function to scan budget columns into a model
+func scanBudget(row rowScanner) (models.Budget, error) {
+	budget := models.Budget{}
+	err := row.Scan(&budget.ID, &budget.Name, &budget.Amount, &budget.Currency, &budget.Period, &budget.Tag, &budget.CategoryID, &budget.Thresholds, &budget.CreatedAt, &budget.UpdatedAt)
+	return budget, err
+}
+
+// scanBudgetAlert is a function to scan budget alert columns into a model
+func scanBudgetAlert(row rowScanner) (models.BudgetAlert, error) {
+	alert := models.BudgetAlert{}
+	err := row.Scan(&alert.ID, &alert.BudgetID, &alert.PeriodStart, &alert.Threshold, &alert.Spent, &alert.ExpenseID, &alert.CreatedAt)
+	return alert, err
+}
+
+// BudgetRepository is a repository for budget
+type BudgetRepository struct {
+	db *sql.DB
+}
+
+// NewBudgetRepository is a function to create new budget repository
+func NewBudgetRepository(db *sql.DB) *BudgetRepository {
+	return &BudgetRepository{
+		db: db,
+	}
+}
+
+// FindAll is a function to get an owner's budgets
+func (r *BudgetRepository) FindAll(ctx context.Context, ownerID string) ([]models.Budget, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	return r.query(ctx, "SELECT "+budgetColumns+" FROM budgets WHERE owner_id = $1 ORDER BY id ASC", ownerID)
+}
+
+// FindMatching is a function to get an owner's budgets in the currency of an expense scoped by one of its tags,
+// its category or an ancestor of its category
+func (r *BudgetRepository) FindMatching(ctx context.Context, ownerID string, expense models.Expense) ([]models.Budget, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	SELECT ` + budgetColumns + ` FROM budgets
+	WHERE owner_id = $1 AND currency = $2 AND (tag = ANY($3) OR category_id IN (
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id = $4
+			UNION ALL
+			SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT id FROM ancestors
+	))
+	ORDER BY id ASC`
+
+	return r.query(ctx, sqlCommand, ownerID, expense.Currency, pq.Array(expense.Tags), expense.CategoryID)
+}
+
+// FindOne is a function to get an owner's budget by id
+func (r *BudgetRepository) FindOne(ctx context.Context, ownerID string, id string) (models.Budget, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	return scanBudget(r.db.QueryRowContext(ctx, "SELECT "+budgetColumns+" FROM budgets WHERE id = $1 AND owner_id = $2", id, ownerID))
+}
+
+// Create is a function to create a new budget for an owner
+func (r *BudgetRepository) Create(ctx context.Context, ownerID string, budget models.Budget) (models.Budget, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	INSERT INTO budgets (owner_id, name, amount, currency, period, tag, category_id, thresholds) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING ` + budgetColumns
+
+	return scanBudget(r.db.QueryRowContext(ctx, sqlCommand, ownerID, budget.Name, budget.Amount, budget.Currency, budget.Period, budget.Tag, budget.CategoryID, budget.Thresholds))
+}
+
+// Update is a function to update an owner's budget by id
+func (r *BudgetRepository) Update(ctx context.Context, ownerID string, id string, budget models.Budget) (models.Budget, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	UPDATE budgets SET name = $3, amount = $4, currency = $5, period = $6, tag = $7, category_id = $8, thresholds = $9, updated_at = NOW()
+	WHERE id = $1 AND owner_id = $2
+	RETURNING ` + budgetColumns
+
+	return scanBudget(r.db.QueryRowContext(ctx, sqlCommand, id, ownerID, budget.Name, budget.Amount, budget.Currency, budget.Period, budget.Tag, budget.CategoryID, budget.Thresholds))
+}
+
+// Delete is a function to delete an owner's budget by id
+func (r *BudgetRepository) Delete(ctx context.Context, ownerID string, id string) error {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM budgets WHERE id = $1 AND owner_id = $2", id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(result)
+}
+
+// Spent is a function to sum and count an owner's expenses within a budget scope between two dates inclusive
+func (r *BudgetRepository) Spent(ctx context.Context, ownerID string, budget models.Budget, from string, to string) (money.Amount, int, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	query := types.ExpenseQuery{Currency: budget.Currency, SpentFrom: from, SpentTo: to}
+	if budget.Tag != nil {
+		query.Tags = []string{*budget.Tag}
+	}
+	if budget.CategoryID != nil {
+		query.Category = *budget.CategoryID
+	}
+
+	conditions, args := expenseFilter(ownerID, query)
+	sqlCommand := "SELECT COALESCE(SUM(amount), 0), COUNT(*) FROM expenses WHERE " + strings.Join(conditions, " AND ")
+
+	var spent money.Amount
+	var count int
+	if err := r.db.QueryRowContext(ctx, sqlCommand, args...).Scan(&spent, &count); err != nil {
+		return 0, 0, err
+	}
+
+	return spent, count, nil
+}
+
+// CreateAlert is a function to record a budget threshold reached in a period, false is returned
+// with no alert when the threshold was already reached in that period
+func (r *BudgetRepository) CreateAlert(ctx context.Context, alert models.BudgetAlert) (models.BudgetAlert, bool, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	INSERT INTO budget_alerts (budget_id, period_start, threshold, spent, expense_id) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (budget_id, period_start, threshold) DO NOTHING
+	RETURNING ` + budgetAlertColumns
+
+	created, err := scanBudgetAlert(r.db.QueryRowContext(ctx, sqlCommand, alert.BudgetID, alert.PeriodStart, alert.Threshold, alert.Spent, alert.ExpenseID))
+	if err == sql.ErrNoRows {
+		return models.BudgetAlert{}, false, nil
+	}
+
+	return created, err == nil, err
+}
+
+// FindAlerts is a function to get the alerts of a budget, latest first
+func (r *BudgetRepository) FindAlerts(ctx context.Context, budgetID string) ([]models.BudgetAlert, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+budgetAlertColumns+" FROM budget_alerts WHERE budget_id = $1 ORDER BY id DESC", budgetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.BudgetAlert{}
+	for rows.Next() {
+		alert, err := scanBudgetAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// query is a function to scan the budgets returned by a query
+func (r *BudgetRepository) query(ctx context.Context, sqlCommand string, args ...interface{}) ([]models.Budget, error) {
+	rows, err := r.db.QueryContext(ctx, sqlCommand, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []models.Budget{}
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, rows.Err()
+}
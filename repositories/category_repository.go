@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// ErrCategoryLocked is an error of deleting a category that expenses locked by the approval workflow are in
+var ErrCategoryLocked = errors.New("category is used by expenses locked by the approval workflow")
+
+// categoryColumns is a list of category columns in the order scanned by scanCategory
+const categoryColumns = "id, name, parent_id"
+
+// scanCategory is a function to scan category columns into a model
+func scanCategory(row rowScanner) (models.Category, error) {
+	category := models.Category{}
+	err := row.Scan(&category.ID, &category.Name, &category.ParentID)
+	return category, err
+}
+
+// CategoryRepository is a repository for category
+type CategoryRepository struct {
+	db    *sql.DB
+	tx    *sql.Tx
+	actor types.Actor
+}
+
+// NewCategoryRepository is a function to create new category repository
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{
+		db: db,
+	}
+}
+
+// WithActor is a function to get a copy of the repository recording actor on the audit events of its changes
+func (r *CategoryRepository) WithActor(actor types.Actor) CategoryRepository {
+	return CategoryRepository{db: r.database(), tx: r.tx, actor: actor}
+}
+
+// WithTx is a function to get a copy of the repository running its queries in a transaction
+func (r *CategoryRepository) WithTx(tx *sql.Tx) CategoryRepository {
+	return CategoryRepository{db: r.database(), tx: tx, actor: r.actor}
+}
+
+// conn is a function to get the transaction the repository is bound to or else its database
+func (r *CategoryRepository) conn() Querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.database()
+}
+
+// database is a function to get the repository database connecting it on first use
+func (r *CategoryRepository) database() *sql.DB {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+	return r.db
+}
+
+// FindAll is a function to get all categories
+func (r *CategoryRepository) FindAll(ctx context.Context) ([]models.Category, error) {
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+categoryColumns+" FROM categories ORDER BY name ASC, id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+// FindOne is a function to get a category by id
+func (r *CategoryRepository) FindOne(ctx context.Context, id string) (models.Category, error) {
+	return scanCategory(r.conn().QueryRowContext(ctx, "SELECT "+categoryColumns+" FROM categories WHERE id = $1", id))
+}
+
+// Create is a function to create a new category
+func (r *CategoryRepository) Create(ctx context.Context, categoryRequest types.CategoryRequest) (models.Category, error) {
+	sqlCommand := "INSERT INTO categories (name, parent_id) VALUES ($1, $2) RETURNING " + categoryColumns
+
+	return scanCategory(r.conn().QueryRowContext(ctx, sqlCommand, strings.TrimSpace(categoryRequest.Name), categoryRequest.ParentID))
+}
+
+// Update is a function to update a category by id
+func (r *CategoryRepository) Update(ctx context.Context, id string, categoryRequest types.CategoryRequest) (models.Category, error) {
+	sqlCommand := "UPDATE categories SET name = $2, parent_id = $3 WHERE id = $1 RETURNING " + categoryColumns
+
+	return scanCategory(r.conn().QueryRowContext(ctx, sqlCommand, id, strings.TrimSpace(categoryRequest.Name), categoryRequest.ParentID))
+}
+
+// Delete is a function to delete a category by id, its draft expenses are left without a category in the same
+// transaction with a new version so the entity tags clients hold for them no longer match, the category is kept
+// with ErrCategoryLocked while any expense locked by the approval workflow, deleted or not, is still in it
+func (r *CategoryRepository) Delete(ctx context.Context, id string) error {
+	tx, err := r.database().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if r.actor.ID != "" {
+		if _, err := tx.ExecContext(ctx, setActorSQL, r.actor.ID, r.actor.RequestID); err != nil {
+			return err
+		}
+	}
+
+	// the lock keeps new expenses from taking the category until it is gone
+	var locked string
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM categories WHERE id = $1 FOR UPDATE", id).Scan(&locked); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE expenses SET category_id = NULL, updated_at = NOW(), version = version + 1 WHERE category_id = $1 AND status = 'draft'", id); err != nil {
+		return err
+	}
+
+	// checked after the drafts are updated so an expense submitted meanwhile is seen, the foreign key
+	// would otherwise clear the category of locked expenses as well
+	var inUse bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM expenses WHERE category_id = $1 AND status <> 'draft')", id).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse {
+		return ErrCategoryLocked
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// HasChildren is a function to check if a category is the parent of other categories
+func (r *CategoryRepository) HasChildren(ctx context.Context, id string) (bool, error) {
+	var exists bool
+	err := r.conn().QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1)", id).Scan(&exists)
+
+	return exists, err
+}
+
+// IsDescendant is a function to check if a category is the given ancestor or below it
+func (r *CategoryRepository) IsDescendant(ctx context.Context, ancestorID string, id string) (bool, error) {
+	sqlCommand := `
+	WITH RECURSIVE descendants AS (
+		SELECT id FROM categories WHERE id = $1
+		UNION ALL
+		SELECT c.id FROM categories c JOIN descendants d ON c.parent_id = d.id
+	)
+	SELECT EXISTS (SELECT 1 FROM descendants WHERE id = $2)
+	`
+
+	var exists bool
+	err := r.conn().QueryRowContext(ctx, sqlCommand, ancestorID, id).Scan(&exists)
+
+	return exists, err
+}
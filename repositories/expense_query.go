@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/money"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// expenseSortColumns is a whitelist of sortable columns on expenses
+var expenseSortColumns = map[string]string{
+	"id":     "id",
+	"amount": "amount",
+	"title":  "title",
+}
+
+// expenseFilter is a function to build where conditions and arguments from an owner and an expense query
+func expenseFilter(ownerID string, query types.ExpenseQuery) ([]string, []interface{}) {
+	conditions := []string{"owner_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{ownerID}
+
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(query.Tags) > 0 {
+		if query.TagMode == "all" {
+			conditions = append(conditions, "tags @> "+arg(pq.Array(query.Tags)))
+		} else {
+			conditions = append(conditions, "tags && "+arg(pq.Array(query.Tags)))
+		}
+	}
+	if query.MinAmount != nil {
+		conditions = append(conditions, "amount >= "+arg(*query.MinAmount))
+	}
+	if query.MaxAmount != nil {
+		conditions = append(conditions, "amount <= "+arg(*query.MaxAmount))
+	}
+	if query.Currency != "" {
+		conditions = append(conditions, "currency = "+arg(money.NormalizeCurrency(query.Currency)))
+	}
+	if query.Title != "" {
+		conditions = append(conditions, "title ILIKE "+arg("%"+escapeLike(query.Title)+"%"))
+	}
+	if query.Status != "" {
+		conditions = append(conditions, "status = "+arg(query.Status))
+	}
+
+	if query.SpentFrom != "" {
+		conditions = append(conditions, "spent_at >= "+arg(query.SpentFrom))
+	}
+	if query.SpentTo != "" {
+		conditions = append(conditions, "spent_at <= "+arg(query.SpentTo))
+	}
+	if query.Category != "" {
+		conditions = append(conditions, `category_id IN (
+			WITH RECURSIVE subcategories AS (
+				SELECT id FROM categories WHERE id = `+arg(query.Category)+`
+				UNION ALL
+				SELECT c.id FROM categories c JOIN subcategories s ON c.parent_id = s.id
+			)
+			SELECT id FROM subcategories
+		)`)
+	}
+
+	return conditions, args
+}
+
+// expenseOrder is a function to resolve the sort column and direction of an expense query
+func expenseOrder(query types.ExpenseQuery) (string, string) {
+	column, ok := expenseSortColumns[query.Sort]
+	if !ok {
+		column = "id"
+	}
+
+	if query.Order == "desc" {
+		return column, "DESC"
+	}
+	return column, "ASC"
+}
+
+// expenseOrderBy is a function to build an order by clause with id as tie breaker
+func expenseOrderBy(column string, direction string) string {
+	if column == "id" {
+		return "id " + direction
+	}
+	return fmt.Sprintf("%s %s, id %s", column, direction, direction)
+}
+
+// escapeLike is a function to escape LIKE wildcards in a search term
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term)
+}
@@ -1,8 +1,10 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/lib/pq"
 	"github.com/walkmanrd/assessment/configs"
@@ -10,9 +12,42 @@ import (
 	"github.com/walkmanrd/assessment/types"
 )
 
+// expenseColumns is a list of expense columns in the order scanned by scanExpense
+const expenseColumns = "id, title, amount, currency, note, tags, category_id, TO_CHAR(spent_at, 'YYYY-MM-DD'), created_at, updated_at, status, version"
+
+// insertExpenseSQL is a statement to insert an expense for an owner returning the expense columns
+const insertExpenseSQL = `
+	INSERT INTO expenses (id, owner_id, title, amount, currency, note, tags, category_id, spent_at) values (DEFAULT, $1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING ` + expenseColumns
+
+// insertExpenseArgs is a function to build the arguments of insertExpenseSQL
+func insertExpenseArgs(ownerID string, expenseRequest types.ExpenseRequest) []interface{} {
+	return []interface{}{
+		ownerID, expenseRequest.Title, expenseRequest.Amount, expenseRequest.Currency, expenseRequest.Note,
+		pq.Array(expenseRequest.Tags), expenseRequest.CategoryID, expenseRequest.SpentAt,
+	}
+}
+
+// rowScanner is an interface for both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanExpense is a function to scan expense columns into a model
+func scanExpense(row rowScanner) (models.Expense, error) {
+	expense := models.Expense{}
+	err := row.Scan(&expense.ID, &expense.Title, &expense.Amount, &expense.Currency, &expense.Note, &expense.Tags, &expense.CategoryID, &expense.SpentAt, &expense.CreatedAt, &expense.UpdatedAt, &expense.Status, &expense.Version)
+	return expense, err
+}
+
+// setActorSQL is a statement to tag the audit events written by the rest of a transaction with an actor
+const setActorSQL = `SELECT set_config('app.actor_id', $1, true), set_config('app.request_id', $2, true)`
+
 // ExpenseRepository is a repository for expense
 type ExpenseRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	tx    *sql.Tx
+	actor types.Actor
 }
 
 // NewExpenseRepository is a function to create new expense repository
@@ -22,117 +57,350 @@ func NewExpenseRepository(db *sql.DB) *ExpenseRepository {
 	}
 }
 
-// FindAll is a function to get all expenses
-func (r *ExpenseRepository) FindAll() ([]models.Expense, error) {
+// Begin is a function to start a transaction on the repository database with opts, nil opts use the default isolation,
+// the audit events written in the transaction are tagged with the repository actor
+func (r *ExpenseRepository) Begin(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := r.database().BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.actor.ID != "" {
+		if _, err := tx.ExecContext(ctx, setActorSQL, r.actor.ID, r.actor.RequestID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+// WithTx is a function to get a copy of the repository running its queries in a transaction
+func (r *ExpenseRepository) WithTx(tx *sql.Tx) ExpenseRepository {
+	return ExpenseRepository{db: r.database(), tx: tx, actor: r.actor}
+}
+
+// InTx is a function to check if the repository runs its queries in a transaction
+func (r *ExpenseRepository) InTx() bool {
+	return r.tx != nil
+}
+
+// WithActor is a function to get a copy of the repository recording actor on the audit events of its changes
+func (r *ExpenseRepository) WithActor(actor types.Actor) ExpenseRepository {
+	return ExpenseRepository{db: r.database(), tx: r.tx, actor: actor}
+}
+
+// audited is a function to run a change in a transaction tagged with the repository actor,
+// the change runs as is when the repository is already in a transaction or has no actor
+func (r *ExpenseRepository) audited(ctx context.Context, change func(q Querier) error) error {
+	if r.tx != nil || r.actor.ID == "" {
+		return change(r.conn())
+	}
+
+	tx, err := r.Begin(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := change(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// conn is a function to get the transaction the repository is bound to or else its database
+func (r *ExpenseRepository) conn() Querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.database()
+}
+
+// database is a function to get the repository database connecting it on first use,
+// copies of the repository share the database so they don't open a connection pool of their own
+func (r *ExpenseRepository) database() *sql.DB {
 	if r.db == nil {
 		r.db = configs.ConnectDatabase()
 	}
+	return r.db
+}
+
+// FindAll is a function to get a page of an owner's expenses matching a query after a cursor
+func (r *ExpenseRepository) FindAll(ctx context.Context, ownerID string, query types.ExpenseQuery, cursor *types.ExpenseCursor, limit int) ([]models.Expense, error) {
+	conditions, args := expenseFilter(ownerID, query)
+	column, direction := expenseOrder(query)
+
+	if cursor != nil {
+		comparator := ">"
+		if direction == "DESC" {
+			comparator = "<"
+		}
+
+		if column == "id" {
+			args = append(args, cursor.ID)
+			conditions = append(conditions, fmt.Sprintf("id %s $%d", comparator, len(args)))
+		} else {
+			args = append(args, cursor.Value, cursor.ID)
+			conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", column, comparator, len(args)-1, len(args)))
+		}
+	}
 
-	stmt, err := r.db.Prepare("SELECT id, title, amount, note, tags FROM expenses ORDER BY id ASC")
+	args = append(args, limit)
+	sqlCommand := fmt.Sprintf(
+		"SELECT %s FROM expenses WHERE %s ORDER BY %s LIMIT $%d",
+		expenseColumns, strings.Join(conditions, " AND "), expenseOrderBy(column, direction), len(args),
+	)
+
+	stmt, err := r.conn().PrepareContext(ctx, sqlCommand)
 	if err != nil {
 		return nil, err
 	}
+	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	expenses := []models.Expense{}
 
 	for rows.Next() {
-		expense := models.Expense{}
-		err := rows.Scan(&expense.ID, &expense.Title, &expense.Amount, &expense.Note, &expense.Tags)
+		expense, err := scanExpense(rows)
 		if err != nil {
 			return nil, err
 		}
 		expenses = append(expenses, expense)
 	}
 
-	return expenses, nil
+	return expenses, rows.Err()
 }
 
-// FindOne is a function to get an expenses by id
-func (r *ExpenseRepository) FindOne(id string) (models.Expense, error) {
-	if r.db == nil {
-		r.db = configs.ConnectDatabase()
-	}
+// Each is a function to stream an owner's expenses matching a query to fn one row at a time,
+// rows are read from the result cursor as fn consumes them instead of being collected first
+func (r *ExpenseRepository) Each(ctx context.Context, ownerID string, query types.ExpenseQuery, fn func(models.Expense) error) error {
+	conditions, args := expenseFilter(ownerID, query)
+	column, direction := expenseOrder(query)
+	sqlCommand := fmt.Sprintf(
+		"SELECT %s FROM expenses WHERE %s ORDER BY %s",
+		expenseColumns, strings.Join(conditions, " AND "), expenseOrderBy(column, direction),
+	)
 
-	stmt, err := r.db.Prepare("SELECT id, title, amount, note, tags FROM expenses WHERE id = $1")
+	rows, err := r.conn().QueryContext(ctx, sqlCommand, args...)
 	if err != nil {
-		return models.Expense{}, err
+		return err
 	}
+	defer rows.Close()
 
-	row := stmt.QueryRow(id)
-	expense := models.Expense{}
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(expense); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Count is a function to count an owner's expenses matching a query
+func (r *ExpenseRepository) Count(ctx context.Context, ownerID string, query types.ExpenseQuery) (int, error) {
+	conditions, args := expenseFilter(ownerID, query)
+	sqlCommand := "SELECT COUNT(*) FROM expenses WHERE " + strings.Join(conditions, " AND ")
 
-	err = row.Scan(&expense.ID, &expense.Title, &expense.Amount, &expense.Note, &expense.Tags)
+	stmt, err := r.conn().PrepareContext(ctx, sqlCommand)
 	if err != nil {
-		return models.Expense{}, err
+		return 0, err
 	}
+	defer stmt.Close()
 
-	return expense, nil
+	var total int
+	if err := stmt.QueryRowContext(ctx, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
 }
 
-// Create is a function to create a new expense
-func (r *ExpenseRepository) Create(expenseRequest types.ExpenseRequest) (models.Expense, error) {
-	if r.db == nil {
-		r.db = configs.ConnectDatabase()
+// FindOne is a function to get an owner's expense by id
+func (r *ExpenseRepository) FindOne(ctx context.Context, ownerID string, id string) (models.Expense, error) {
+	stmt, err := r.conn().PrepareContext(ctx, "SELECT "+expenseColumns+" FROM expenses WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL")
+	if err != nil {
+		return models.Expense{}, err
 	}
+	defer stmt.Close()
 
-	sqlCommand := `
-	INSERT INTO expenses (id, title, amount, note, tags) values (DEFAULT, $1, $2, $3, $4)
-	RETURNING id, title, amount, note, tags
-	`
-	expense := models.Expense{
-		Title:  expenseRequest.Title,
-		Amount: expenseRequest.Amount,
-		Note:   expenseRequest.Note,
-		Tags:   expenseRequest.Tags,
+	expense, err := scanExpense(stmt.QueryRowContext(ctx, id, ownerID))
+	if err != nil {
+		return models.Expense{}, err
 	}
 
-	tags := pq.Array(expense.Tags)
-	row := r.db.QueryRow(sqlCommand, &expense.Title, &expense.Amount, &expense.Note, tags)
-	err := row.Scan(&expense.ID, &expense.Title, &expense.Amount, &expense.Note, &expense.Tags)
+	return expense, nil
+}
+
+// Create is a function to create a new expense for an owner
+func (r *ExpenseRepository) Create(ctx context.Context, ownerID string, expenseRequest types.ExpenseRequest) (models.Expense, error) {
+	var expense models.Expense
+	err := r.audited(ctx, func(q Querier) (err error) {
+		expense, err = scanExpense(q.QueryRowContext(ctx, insertExpenseSQL, insertExpenseArgs(ownerID, expenseRequest)...))
+		return err
+	})
 
 	if err != nil {
-		fmt.Println("can't scan id on ExpenseRepository", err)
 		return models.Expense{}, err
 	}
 
 	return expense, nil
 }
 
-// Update is a function to update an expense by id
-func (r *ExpenseRepository) Update(id string, expenseRequest types.ExpenseRequest) (models.Expense, error) {
-	if r.db == nil {
-		r.db = configs.ConnectDatabase()
+// CreateMany is a function to create expenses for an owner in a single transaction, the transaction is
+// rolled back when commit is false or any insert fails, the index of a request whose values failed is returned with its error
+func (r *ExpenseRepository) CreateMany(ctx context.Context, ownerID string, expenseRequests []types.ExpenseRequest, commit bool) ([]models.Expense, int, error) {
+	tx, err := r.Begin(ctx, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertExpenseSQL)
+	if err != nil {
+		return nil, -1, err
 	}
+	defer stmt.Close()
 
-	sqlCommand := `UPDATE expenses SET title = $2, amount = $3, note = $4, tags = $5 WHERE id = $1 RETURNING id, title, amount, note, tags;`
+	expenses := make([]models.Expense, 0, len(expenseRequests))
+	for i, expenseRequest := range expenseRequests {
+		expense, err := scanExpense(stmt.QueryRowContext(ctx, insertExpenseArgs(ownerID, expenseRequest)...))
+		if err != nil && IsRowError(err) {
+			return nil, i, err
+		}
+		if err != nil {
+			return nil, -1, err
+		}
+		expenses = append(expenses, expense)
+	}
+
+	if !commit {
+		return expenses, -1, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, -1, err
+	}
+
+	return expenses, -1, nil
+}
+
+// Update is a function to update an owner's expense by id, optionally only when its version is one of versions
+func (r *ExpenseRepository) Update(ctx context.Context, ownerID string, id string, expenseRequest types.ExpenseRequest, versions []int) (models.Expense, error) {
+	sqlCommand := `UPDATE expenses SET title = $3, amount = $4, currency = $5, note = $6, tags = $7, category_id = $8, spent_at = COALESCE($9, spent_at), updated_at = NOW(), version = version + 1 WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft'`
+	args := []interface{}{id, ownerID, expenseRequest.Title, expenseRequest.Amount, expenseRequest.Currency, expenseRequest.Note, pq.Array(expenseRequest.Tags), expenseRequest.CategoryID, nullIfEmpty(expenseRequest.SpentAt)}
 
-	expense := models.Expense{
-		ID:     id,
-		Title:  expenseRequest.Title,
-		Amount: expenseRequest.Amount,
-		Note:   expenseRequest.Note,
-		Tags:   expenseRequest.Tags,
+	if versions != nil {
+		sqlCommand += ` AND version = ANY($10)`
+		args = append(args, pq.Array(versions))
 	}
+	sqlCommand += ` RETURNING ` + expenseColumns + `;`
 
-	stmt, err := r.db.Prepare(sqlCommand)
+	var expense models.Expense
+	err := r.audited(ctx, func(q Querier) error {
+		stmt, err := q.PrepareContext(ctx, sqlCommand)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		expense, err = scanExpense(stmt.QueryRowContext(ctx, args...))
+		return err
+	})
 
 	if err != nil {
-		fmt.Println("can't prepare statement on ExpenseRepository", err)
 		return models.Expense{}, err
 	}
 
-	row := stmt.QueryRow(id, &expense.Title, &expense.Amount, &expense.Note, &expense.Tags)
-	err = row.Scan(&expense.ID, &expense.Title, &expense.Amount, &expense.Note, &expense.Tags)
+	return expense, nil
+}
+
+// Delete is a function to soft delete an owner's expense by id
+func (r *ExpenseRepository) Delete(ctx context.Context, ownerID string, id string) error {
+	return r.audited(ctx, func(q Querier) error {
+		stmt, err := q.PrepareContext(ctx, "UPDATE expenses SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND status = 'draft'")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		result, err := stmt.ExecContext(ctx, id, ownerID)
+		if err != nil {
+			return err
+		}
+
+		return affectedOne(result)
+	})
+}
+
+// Restore is a function to restore an owner's soft deleted expense by id
+func (r *ExpenseRepository) Restore(ctx context.Context, ownerID string, id string) (models.Expense, error) {
+	sqlCommand := `UPDATE expenses SET deleted_at = NULL, updated_at = NOW(), version = version + 1 WHERE id = $1 AND owner_id = $2 AND deleted_at IS NOT NULL RETURNING ` + expenseColumns + `;`
+
+	var expense models.Expense
+	err := r.audited(ctx, func(q Querier) error {
+		stmt, err := q.PrepareContext(ctx, sqlCommand)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
 
+		expense, err = scanExpense(stmt.QueryRowContext(ctx, id, ownerID))
+		return err
+	})
 	if err != nil {
-		fmt.Println("can't scan id on ExpenseRepository", err)
 		return models.Expense{}, err
 	}
 
 	return expense, nil
 }
+
+// Purge is a function to permanently delete an expense of any owner by id
+func (r *ExpenseRepository) Purge(ctx context.Context, id string) error {
+	return r.audited(ctx, func(q Querier) error {
+		stmt, err := q.PrepareContext(ctx, "DELETE FROM expenses WHERE id = $1")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		result, err := stmt.ExecContext(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return affectedOne(result)
+	})
+}
+
+// nullIfEmpty is a function to pass an empty string as NULL
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// affectedOne is a function to return sql.ErrNoRows when no row was affected
+func affectedOne(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/types"
+)
+
+const (
+	// searchStartSel and searchStopSel are private use characters ts_headline puts around matches,
+	// they are turned into <mark> tags once the rest of the text is HTML escaped
+	searchStartSel = "\ue000"
+	searchStopSel  = "\ue001"
+	// searchTitleOptions and searchNoteOptions are ts_headline options for the title and note of a search hit
+	searchTitleOptions = "StartSel=" + searchStartSel + ", StopSel=" + searchStopSel + ", HighlightAll=true"
+	searchNoteOptions  = "StartSel=" + searchStartSel + ", StopSel=" + searchStopSel + ", MaxWords=20, MinWords=5, MaxFragments=2"
+)
+
+// searchMarks is a replacer turning the match delimiters of a headline into <mark> tags
+var searchMarks = strings.NewReplacer(searchStartSel, "<mark>", searchStopSel, "</mark>")
+
+// markHeadline is a function to HTML escape a headline keeping its matches wrapped in <mark> tags
+func markHeadline(headline string) string {
+	return searchMarks.Replace(html.EscapeString(headline))
+}
+
+// searchFilter is a function to build the conditions and arguments of an owner's expenses matching a text search query
+func searchFilter(ownerID string, query types.ExpenseSearchQuery, tsquery string) (string, []interface{}) {
+	conditions, args := expenseFilter(ownerID, types.ExpenseQuery{
+		Tags:      query.Tags,
+		Category:  query.Category,
+		SpentFrom: query.SpentFrom,
+		SpentTo:   query.SpentTo,
+	})
+
+	args = append(args, tsquery)
+	from := fmt.Sprintf("expenses, to_tsquery('english', $%d) AS q", len(args))
+	conditions = append(conditions, "search @@ q")
+
+	return from + " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Search is a function to get a page of an owner's expenses matching a text search query ordered by rank,
+// tsquery must be a valid to_tsquery expression
+func (r *ExpenseRepository) Search(ctx context.Context, ownerID string, query types.ExpenseSearchQuery, tsquery string, limit int) ([]types.ExpenseSearchHit, error) {
+	from, args := searchFilter(ownerID, query, tsquery)
+
+	args = append(args, limit, query.Offset)
+	sqlCommand := fmt.Sprintf(`
+	SELECT %s, ts_rank(search, q) AS rank,
+		ts_headline('english', title, q, '%s'),
+		ts_headline('english', COALESCE(note, ''), q, '%s'),
+		ARRAY(SELECT tag FROM UNNEST(tags) AS tag WHERE to_tsvector('english', tag) @@ q)
+	FROM %s
+	ORDER BY rank DESC, id DESC LIMIT $%d OFFSET $%d`,
+		expenseColumns, searchTitleOptions, searchNoteOptions, from, len(args)-1, len(args),
+	)
+
+	rows, err := r.conn().QueryContext(ctx, sqlCommand, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []types.ExpenseSearchHit{}
+	for rows.Next() {
+		hit := types.ExpenseSearchHit{}
+		var tags pq.StringArray
+		err := rows.Scan(
+			&hit.Expense.ID, &hit.Expense.Title, &hit.Expense.Amount, &hit.Expense.Currency, &hit.Expense.Note, &hit.Expense.Tags,
+			&hit.Expense.CategoryID, &hit.Expense.SpentAt, &hit.Expense.CreatedAt, &hit.Expense.UpdatedAt, &hit.Expense.Status, &hit.Expense.Version,
+			&hit.Rank, &hit.Highlight.Title, &hit.Highlight.Note, &tags,
+		)
+		if err != nil {
+			return nil, err
+		}
+		hit.Highlight.Title = markHeadline(hit.Highlight.Title)
+		hit.Highlight.Note = markHeadline(hit.Highlight.Note)
+		hit.Highlight.Tags = make([]string, len(tags))
+		for i, tag := range tags {
+			hit.Highlight.Tags[i] = html.EscapeString(tag)
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+// SearchCount is a function to count an owner's expenses matching a text search query
+func (r *ExpenseRepository) SearchCount(ctx context.Context, ownerID string, query types.ExpenseSearchQuery, tsquery string) (int, error) {
+	from, args := searchFilter(ownerID, query, tsquery)
+
+	var total int
+	if err := r.conn().QueryRowContext(ctx, "SELECT COUNT(*) FROM "+from, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/walkmanrd/assessment/models"
+)
+
+// transitionExpenseSQL is a statement to move an expense from one status to another and record the transition,
+// no row is returned when the expense is not in the from status anymore
+const transitionExpenseSQL = `
+	WITH updated AS (
+		UPDATE expenses SET status = $3, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND status = $2 AND deleted_at IS NULL
+		RETURNING ` + expenseColumns + `
+	), recorded AS (
+		INSERT INTO expense_transitions (expense_id, from_status, to_status, actor_id, comment)
+		SELECT id, $2, $3, $4, $5 FROM updated
+	)
+	SELECT * FROM updated`
+
+// FindWithOwner is a function to get an expense by id of any owner together with the id of its owner
+func (r *ExpenseRepository) FindWithOwner(ctx context.Context, id string) (string, models.Expense, error) {
+	stmt, err := r.conn().PrepareContext(ctx, "SELECT owner_id, "+expenseColumns+" FROM expenses WHERE id = $1 AND deleted_at IS NULL")
+	if err != nil {
+		return "", models.Expense{}, err
+	}
+	defer stmt.Close()
+
+	var ownerID string
+	expense := models.Expense{}
+	err = stmt.QueryRowContext(ctx, id).Scan(&ownerID, &expense.ID, &expense.Title, &expense.Amount, &expense.Currency, &expense.Note, &expense.Tags, &expense.CategoryID, &expense.SpentAt, &expense.CreatedAt, &expense.UpdatedAt, &expense.Status, &expense.Version)
+	if err != nil {
+		return "", models.Expense{}, err
+	}
+
+	return ownerID, expense, nil
+}
+
+// Transition is a function to change the status of an expense by id from one status to another
+// recording who made the change and why
+func (r *ExpenseRepository) Transition(ctx context.Context, id string, from string, to string, actorID string, comment string) (models.Expense, error) {
+	var expense models.Expense
+	err := r.audited(ctx, func(q Querier) error {
+		stmt, err := q.PrepareContext(ctx, transitionExpenseSQL)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		expense, err = scanExpense(stmt.QueryRowContext(ctx, id, from, to, actorID, comment))
+		return err
+	})
+
+	return expense, err
+}
+
+// FindTransitions is a function to get the status changes of an expense by id oldest first
+func (r *ExpenseRepository) FindTransitions(ctx context.Context, id string) ([]models.ExpenseTransition, error) {
+	rows, err := r.conn().QueryContext(ctx, `
+	SELECT t.id, t.expense_id, t.from_status, t.to_status, t.actor_id, u.name, t.comment, t.created_at
+	FROM expense_transitions t LEFT JOIN users u ON u.id = t.actor_id
+	WHERE t.expense_id = $1
+	ORDER BY t.created_at, t.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []models.ExpenseTransition{}
+
+	for rows.Next() {
+		transition := models.ExpenseTransition{}
+		err := rows.Scan(&transition.ID, &transition.ExpenseID, &transition.From, &transition.To, &transition.ActorID, &transition.ActorName, &transition.Comment, &transition.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+	}
+
+	return transitions, rows.Err()
+}
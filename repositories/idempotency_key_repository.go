@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+)
+
+// IdempotencyKeyRepository is a repository for idempotency keys
+type IdempotencyKeyRepository struct {
+	db *sql.DB
+}
+
+// NewIdempotencyKeyRepository is a function to create new idempotency key repository
+func NewIdempotencyKeyRepository(db *sql.DB) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{
+		db: db,
+	}
+}
+
+// Claim is a function to reserve a key for a request until its lease runs out, an expired key is taken over,
+// it returns false when the key is held by a live record
+func (r *IdempotencyKeyRepository) Claim(ctx context.Context, ownerID string, key string, requestHash string, lease time.Duration) (bool, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	INSERT INTO idempotency_keys (owner_id, key, request_hash, expires_at) VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 millisecond')
+	ON CONFLICT (owner_id, key) DO UPDATE SET
+		request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL, etag = NULL, response_body = NULL,
+		created_at = NOW(), expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at <= NOW()
+	RETURNING key
+	`
+
+	var claimed string
+	err := r.db.QueryRowContext(ctx, sqlCommand, ownerID, key, requestHash, lease.Milliseconds()).Scan(&claimed)
+
+	switch err {
+	case sql.ErrNoRows:
+		return false, nil
+	case nil:
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+// FindOne is a function to get a live idempotency key of an owner
+func (r *IdempotencyKeyRepository) FindOne(ctx context.Context, ownerID string, key string) (models.IdempotencyKey, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	SELECT owner_id, key, request_hash, status_code, COALESCE(content_type, ''), COALESCE(etag, ''), response_body
+	FROM idempotency_keys WHERE owner_id = $1 AND key = $2 AND expires_at > NOW()
+	`
+
+	record := models.IdempotencyKey{}
+	err := r.db.QueryRowContext(ctx, sqlCommand, ownerID, key).Scan(
+		&record.OwnerID, &record.Key, &record.RequestHash, &record.StatusCode, &record.ContentType, &record.ETag, &record.ResponseBody,
+	)
+
+	return record, err
+}
+
+// Complete is a function to record the response of a claimed key keeping it for replay for ttl
+func (r *IdempotencyKeyRepository) Complete(ctx context.Context, record models.IdempotencyKey, ttl time.Duration) error {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	UPDATE idempotency_keys SET status_code = $4, content_type = $5, etag = $6, response_body = $7,
+		expires_at = NOW() + $8 * INTERVAL '1 millisecond'
+	WHERE owner_id = $1 AND key = $2 AND request_hash = $3 AND status_code IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, sqlCommand, record.OwnerID, record.Key, record.RequestHash, record.StatusCode, record.ContentType, record.ETag, record.ResponseBody, ttl.Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(result)
+}
+
+// Renew is a function to push the lease of a key still in flight forward, a key whose response is recorded
+// or that was taken over by another request is left alone
+func (r *IdempotencyKeyRepository) Renew(ctx context.Context, ownerID string, key string, requestHash string, lease time.Duration) error {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	UPDATE idempotency_keys SET expires_at = NOW() + $4 * INTERVAL '1 millisecond'
+	WHERE owner_id = $1 AND key = $2 AND request_hash = $3 AND status_code IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, sqlCommand, ownerID, key, requestHash, lease.Milliseconds())
+	return err
+}
+
+// Release is a function to drop an in flight key so the request can be retried
+func (r *IdempotencyKeyRepository) Release(ctx context.Context, ownerID string, key string, requestHash string) error {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE owner_id = $1 AND key = $2 AND request_hash = $3 AND status_code IS NULL", ownerID, key, requestHash)
+	return err
+}
+
+// DeleteExpired is a function to delete keys past their expiry
+func (r *IdempotencyKeyRepository) DeleteExpired(ctx context.Context) error {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= NOW()")
+	return err
+}
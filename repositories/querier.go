@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// codeSerializationFailure is a PostgreSQL error code of a transaction that can't be serialized with a concurrent one
+	codeSerializationFailure = "40001"
+	// codeDeadlockDetected is a PostgreSQL error code of a transaction aborted to break a deadlock
+	codeDeadlockDetected = "40P01"
+	// classDataException is a PostgreSQL error class of a value a column can't hold
+	classDataException = "22"
+	// classIntegrityConstraintViolation is a PostgreSQL error class of a row breaking a constraint
+	classIntegrityConstraintViolation = "23"
+)
+
+// Querier is an interface for the query methods shared by *sql.DB and *sql.Tx,
+// a repository runs its statements on a Querier so the same code works in and out of a transaction
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ Querier = (*sql.DB)(nil)
+	_ Querier = (*sql.Tx)(nil)
+)
+
+// IsRetryable is a function to check if an error aborted a transaction that may succeed when run again
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == codeSerializationFailure || pqErr.Code == codeDeadlockDetected
+}
+
+// IsRowError is a function to check if an error was caused by the values of the row written,
+// rather than by the connection, a timeout or a cancellation
+func IsRowError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	class := string(pqErr.Code.Class())
+	return class == classDataException || class == classIntegrityConstraintViolation
+}
@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+)
+
+// recurringExpenseColumns is a list of recurring expense columns in the order scanned by scanRecurringExpense
+const recurringExpenseColumns = "id, title, amount, currency, note, tags, category_id, frequency, interval_count, " +
+	"TO_CHAR(starts_on, 'YYYY-MM-DD'), TO_CHAR(ends_on, 'YYYY-MM-DD'), max_count, position, TO_CHAR(next_on, 'YYYY-MM-DD'), paused, created_at, updated_at"
+
+// scanRecurringExpense is a function to scan recurring expense columns into a model
+func scanRecurringExpense(row rowScanner) (models.RecurringExpense, error) {
+	recurringExpense := models.RecurringExpense{}
+	err := row.Scan(
+		&recurringExpense.ID, &recurringExpense.Title, &recurringExpense.Amount, &recurringExpense.Currency, &recurringExpense.Note,
+		&recurringExpense.Tags, &recurringExpense.CategoryID, &recurringExpense.Frequency, &recurringExpense.Interval,
+		&recurringExpense.StartsOn, &recurringExpense.EndsOn, &recurringExpense.Count, &recurringExpense.Position,
+		&recurringExpense.NextOn, &recurringExpense.Paused, &recurringExpense.CreatedAt, &recurringExpense.UpdatedAt,
+	)
+	return recurringExpense, err
+}
+
+// RecurringExpenseRepository is a repository for recurring expense
+type RecurringExpenseRepository struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// NewRecurringExpenseRepository is a function to create new recurring expense repository
+func NewRecurringExpenseRepository(db *sql.DB) *RecurringExpenseRepository {
+	return &RecurringExpenseRepository{
+		db: db,
+	}
+}
+
+// Begin is a function to start a transaction on the repository database
+func (r *RecurringExpenseRepository) Begin(ctx context.Context) (*sql.Tx, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+	return r.db.BeginTx(ctx, nil)
+}
+
+// WithTx is a function to get a copy of the repository running its queries in a transaction
+func (r *RecurringExpenseRepository) WithTx(tx *sql.Tx) RecurringExpenseRepository {
+	return RecurringExpenseRepository{db: r.db, tx: tx}
+}
+
+// conn is a function to get the transaction the repository is bound to or else its database
+func (r *RecurringExpenseRepository) conn() Querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+	return r.db
+}
+
+// FindAll is a function to get an owner's recurring expenses
+func (r *RecurringExpenseRepository) FindAll(ctx context.Context, ownerID string) ([]models.RecurringExpense, error) {
+	rows, err := r.conn().QueryContext(ctx, "SELECT "+recurringExpenseColumns+" FROM recurring_expenses WHERE owner_id = $1 ORDER BY id ASC", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recurringExpenses := []models.RecurringExpense{}
+	for rows.Next() {
+		recurringExpense, err := scanRecurringExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		recurringExpenses = append(recurringExpenses, recurringExpense)
+	}
+
+	return recurringExpenses, rows.Err()
+}
+
+// FindOne is a function to get an owner's recurring expense by id
+func (r *RecurringExpenseRepository) FindOne(ctx context.Context, ownerID string, id string) (models.RecurringExpense, error) {
+	return scanRecurringExpense(r.conn().QueryRowContext(ctx, "SELECT "+recurringExpenseColumns+" FROM recurring_expenses WHERE id = $1 AND owner_id = $2", id, ownerID))
+}
+
+// Create is a function to create a new recurring expense for an owner
+func (r *RecurringExpenseRepository) Create(ctx context.Context, ownerID string, recurringExpense models.RecurringExpense) (models.RecurringExpense, error) {
+	sqlCommand := `
+	INSERT INTO recurring_expenses (owner_id, title, amount, currency, note, tags, category_id, frequency, interval_count, starts_on, ends_on, max_count, position, next_on)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	RETURNING ` + recurringExpenseColumns
+
+	return scanRecurringExpense(r.conn().QueryRowContext(ctx, sqlCommand,
+		ownerID, recurringExpense.Title, recurringExpense.Amount, recurringExpense.Currency, recurringExpense.Note, recurringExpense.Tags,
+		recurringExpense.CategoryID, recurringExpense.Frequency, recurringExpense.Interval, recurringExpense.StartsOn, recurringExpense.EndsOn,
+		recurringExpense.Count, recurringExpense.Position, recurringExpense.NextOn,
+	))
+}
+
+// Update is a function to update an owner's recurring expense by id
+func (r *RecurringExpenseRepository) Update(ctx context.Context, ownerID string, id string, recurringExpense models.RecurringExpense) (models.RecurringExpense, error) {
+	sqlCommand := `
+	UPDATE recurring_expenses SET title = $3, amount = $4, currency = $5, note = $6, tags = $7, category_id = $8, frequency = $9, interval_count = $10,
+		starts_on = $11, ends_on = $12, max_count = $13, position = $14, next_on = $15, updated_at = NOW()
+	WHERE id = $1 AND owner_id = $2
+	RETURNING ` + recurringExpenseColumns
+
+	return scanRecurringExpense(r.conn().QueryRowContext(ctx, sqlCommand,
+		id, ownerID, recurringExpense.Title, recurringExpense.Amount, recurringExpense.Currency, recurringExpense.Note, recurringExpense.Tags,
+		recurringExpense.CategoryID, recurringExpense.Frequency, recurringExpense.Interval, recurringExpense.StartsOn, recurringExpense.EndsOn,
+		recurringExpense.Count, recurringExpense.Position, recurringExpense.NextOn,
+	))
+}
+
+// SetPaused is a function to pause or resume an owner's recurring expense by id from a position
+func (r *RecurringExpenseRepository) SetPaused(ctx context.Context, ownerID string, id string, paused bool, position int, nextOn *string) (models.RecurringExpense, error) {
+	sqlCommand := `
+	UPDATE recurring_expenses SET paused = $3, position = $4, next_on = $5, updated_at = NOW()
+	WHERE id = $1 AND owner_id = $2
+	RETURNING ` + recurringExpenseColumns
+
+	return scanRecurringExpense(r.conn().QueryRowContext(ctx, sqlCommand, id, ownerID, paused, position, nextOn))
+}
+
+// Delete is a function to delete an owner's recurring expense by id, its expenses are kept
+func (r *RecurringExpenseRepository) Delete(ctx context.Context, ownerID string, id string) error {
+	result, err := r.conn().ExecContext(ctx, "DELETE FROM recurring_expenses WHERE id = $1 AND owner_id = $2", id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(result)
+}
+
+// ClaimDue is a function to lock the recurring expense due the longest on a date, schedules locked
+// by another transaction are skipped so replicas materialize different schedules side by side,
+// and so are schedules waiting to be retried after a failure
+func (r *RecurringExpenseRepository) ClaimDue(ctx context.Context, date string) (models.RecurringExpense, error) {
+	sqlCommand := `
+	SELECT ` + recurringExpenseColumns + ` FROM recurring_expenses
+	WHERE NOT paused AND next_on <= $1 AND (retry_at IS NULL OR retry_at <= NOW())
+	ORDER BY next_on ASC, id ASC
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED`
+
+	return scanRecurringExpense(r.conn().QueryRowContext(ctx, sqlCommand, date))
+}
+
+// Materialize is a function to create the expense of a recurring expense occurrence,
+// false is returned when the occurrence already has its expense
+func (r *RecurringExpenseRepository) Materialize(ctx context.Context, id string, spentAt string) (bool, error) {
+	sqlCommand := `
+	INSERT INTO expenses (owner_id, title, amount, currency, note, tags, category_id, spent_at, recurring_expense_id)
+	SELECT owner_id, title, amount, currency, note, tags, category_id, $2, id FROM recurring_expenses WHERE id = $1
+	ON CONFLICT (recurring_expense_id, spent_at) DO NOTHING`
+
+	result, err := r.conn().ExecContext(ctx, sqlCommand, id, spentAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// Advance is a function to move a recurring expense to its next occurrence clearing its failures
+func (r *RecurringExpenseRepository) Advance(ctx context.Context, id string, position int, nextOn *string) error {
+	_, err := r.conn().ExecContext(ctx, "UPDATE recurring_expenses SET position = $2, next_on = $3, failures = 0, retry_at = NULL WHERE id = $1", id, position, nextOn)
+	return err
+}
+
+// Fail is a function to record a failure to materialize a recurring expense, it isn't claimed again
+// before delay has passed, the delay doubles with every failure in a row up to maxDelay
+func (r *RecurringExpenseRepository) Fail(ctx context.Context, id string, delay time.Duration, maxDelay time.Duration) error {
+	sqlCommand := `
+	UPDATE recurring_expenses SET failures = failures + 1,
+		retry_at = NOW() + LEAST($2 * POWER(2, failures), $3) * INTERVAL '1 second'
+	WHERE id = $1`
+
+	_, err := r.conn().ExecContext(ctx, sqlCommand, id, delay.Seconds(), maxDelay.Seconds())
+	return err
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// reportDateColumn is a column expenses are bucketed and ranged by in reports
+const reportDateColumn = "e.spent_at"
+
+// reportDimension is a struct for the SQL of a report grouping
+type reportDimension struct {
+	key   string
+	label string
+	join  string
+}
+
+// reportDimensions is a whitelist of report groupings
+var reportDimensions = map[string]reportDimension{
+	"tag":      {key: "tag", label: "''", join: "CROSS JOIN UNNEST(e.tags) AS tag"},
+	"category": {key: "COALESCE(e.category_id::text, '')", label: "COALESCE(c.name, '')", join: "LEFT JOIN categories c ON c.id = e.category_id"},
+	"month":    {key: "TO_CHAR(DATE_TRUNC('month', " + reportDateColumn + "), 'YYYY-MM-DD')", label: "''"},
+	"week":     {key: "TO_CHAR(DATE_TRUNC('week', " + reportDateColumn + "), 'YYYY-MM-DD')", label: "''"},
+	"day":      {key: "TO_CHAR(DATE_TRUNC('day', " + reportDateColumn + "), 'YYYY-MM-DD')", label: "''"},
+	"currency": {key: "e.currency", label: "''"},
+}
+
+// ReportRepository is a repository for expense reports
+type ReportRepository struct {
+	db *sql.DB
+}
+
+// NewReportRepository is a function to create new report repository
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{
+		db: db,
+	}
+}
+
+// Summarize is a function to aggregate an owner's expenses per group and currency
+func (r *ReportRepository) Summarize(ctx context.Context, ownerID string, query types.ReportQuery) ([]models.ReportGroup, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	dimension, ok := reportDimensions[query.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown report grouping %q", query.GroupBy)
+	}
+
+	conditions, args := expenseFilter(ownerID, types.ExpenseQuery{Tags: query.Tags, TagMode: query.TagMode, Currency: query.Currency})
+	// expense filter conditions start with a column so they are qualified against the joined tables
+	for i, condition := range conditions {
+		conditions[i] = "e." + condition
+	}
+
+	if query.From != "" {
+		args = append(args, query.From)
+		conditions = append(conditions, fmt.Sprintf("%s >= $%d::date", reportDateColumn, len(args)))
+	}
+	if query.To != "" {
+		args = append(args, query.To)
+		conditions = append(conditions, fmt.Sprintf("%s <= $%d::date", reportDateColumn, len(args)))
+	}
+
+	sqlCommand := fmt.Sprintf(`
+	SELECT %s AS key, %s AS label, e.currency, COUNT(*), SUM(e.amount), ROUND(AVG(e.amount), 4), MIN(e.amount), MAX(e.amount)
+	FROM expenses e %s
+	WHERE %s
+	GROUP BY 1, 2, 3 ORDER BY 1, 3
+	`, dimension.key, dimension.label, dimension.join, strings.Join(conditions, " AND "))
+
+	rows, err := r.db.QueryContext(ctx, sqlCommand, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.ReportGroup{}
+	for rows.Next() {
+		group := models.ReportGroup{}
+		if err := rows.Scan(&group.Key, &group.Label, &group.Currency, &group.Count, &group.Total, &group.Average, &group.Min, &group.Max); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
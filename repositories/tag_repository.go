@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// TagRepository is a repository for the tags of expenses
+type TagRepository struct {
+	db    *sql.DB
+	actor types.Actor
+}
+
+// NewTagRepository is a function to create new tag repository
+func NewTagRepository(db *sql.DB) *TagRepository {
+	return &TagRepository{
+		db: db,
+	}
+}
+
+// WithActor is a function to get a copy of the repository recording actor on the audit events of its changes
+func (r *TagRepository) WithActor(actor types.Actor) TagRepository {
+	return TagRepository{db: r.db, actor: actor}
+}
+
+// FindAll is a function to get an owner's tags with the number of expenses using each
+func (r *TagRepository) FindAll(ctx context.Context, ownerID string) ([]models.Tag, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	SELECT tag, COUNT(*) FROM expenses, UNNEST(tags) AS tag
+	WHERE owner_id = $1 AND deleted_at IS NULL
+	GROUP BY tag ORDER BY COUNT(*) DESC, tag ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlCommand, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		tag := models.Tag{}
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// IsUsed is a function to check if any of an owner's live expenses, draft or not, has one of tags
+func (r *TagRepository) IsUsed(ctx context.Context, ownerID string, tags []string) (bool, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	var used bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM expenses WHERE owner_id = $1 AND tags && $2 AND deleted_at IS NULL)", ownerID, pq.Array(tags)).Scan(&used)
+
+	return used, err
+}
+
+// Replace is a function to replace sources with target in all of an owner's draft expenses in one statement,
+// expenses locked by the approval workflow and deleted ones keep their tags, it returns the number of expenses changed
+func (r *TagRepository) Replace(ctx context.Context, ownerID string, sources []string, target string) (int, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if r.actor.ID != "" {
+		if _, err := tx.ExecContext(ctx, setActorSQL, r.actor.ID, r.actor.RequestID); err != nil {
+			return 0, err
+		}
+	}
+
+	sqlCommand := `
+	UPDATE expenses SET tags = ARRAY(
+		SELECT replaced.tag
+		FROM (
+			SELECT CASE WHEN u.tag = ANY($2) THEN $3 ELSE u.tag END AS tag, MIN(u.position) AS position
+			FROM UNNEST(expenses.tags) WITH ORDINALITY AS u (tag, position)
+			GROUP BY 1
+		) AS replaced
+		ORDER BY replaced.position
+	), updated_at = NOW(), version = version + 1
+	WHERE owner_id = $1 AND tags && $2 AND status = 'draft' AND deleted_at IS NULL
+	`
+
+	result, err := tx.ExecContext(ctx, sqlCommand, ownerID, pq.Array(sources), target)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), tx.Commit()
+}
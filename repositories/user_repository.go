@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+)
+
+// LegacyUserID is an id of the user that owns expenses created before users existed
+const LegacyUserID = "1"
+
+// userColumns is a list of user columns in the order scanned by scanUser
+const userColumns = "id, name, COALESCE(subject, ''), COALESCE(api_key_hash, ''), roles"
+
+// scanUser is a function to scan user columns into a model
+func scanUser(row rowScanner) (models.User, error) {
+	user := models.User{}
+	err := row.Scan(&user.ID, &user.Name, &user.Subject, &user.APIKeyHash, &user.Roles)
+	return user, err
+}
+
+// UserRepository is a repository for user
+type UserRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository is a function to create new user repository
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{
+		db: db,
+	}
+}
+
+// FindOne is a function to get a user by id
+func (r *UserRepository) FindOne(ctx context.Context, id string) (models.User, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	return scanUser(r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id))
+}
+
+// FindByAPIKeyHash is a function to get a user by the hash of an API key
+func (r *UserRepository) FindByAPIKeyHash(ctx context.Context, hash string) (models.User, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	return scanUser(r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE api_key_hash = $1", hash))
+}
+
+// FindOrCreateBySubject is a function to get the user of a token subject, creating it on first sight
+func (r *UserRepository) FindOrCreateBySubject(ctx context.Context, subject string) (models.User, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := `
+	INSERT INTO users (name, subject) VALUES ($1, $1)
+	ON CONFLICT (subject) DO UPDATE SET subject = EXCLUDED.subject
+	RETURNING ` + userColumns
+
+	return scanUser(r.db.QueryRowContext(ctx, sqlCommand, subject))
+}
+
+// Create is a function to create a new user
+func (r *UserRepository) Create(ctx context.Context, name string, apiKeyHash string, roles []string) (models.User, error) {
+	if r.db == nil {
+		r.db = configs.ConnectDatabase()
+	}
+
+	sqlCommand := "INSERT INTO users (name, api_key_hash, roles) VALUES ($1, $2, $3) RETURNING " + userColumns
+
+	return scanUser(r.db.QueryRowContext(ctx, sqlCommand, name, apiKeyHash, pq.Array(roles)))
+}
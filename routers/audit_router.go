@@ -0,0 +1,19 @@
+package routers
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/controllers"
+)
+
+// AuditRouter is a function to set audit routes on resource path /audit,
+// the audit controller runs its queries on db
+func AuditRouter(e *echo.Group, db *sql.DB) {
+
+	// AuditController is a struct for audit controller
+	auditController := controllers.NewAuditController(db)
+
+	// Setting up routes
+	e.GET("", auditController.Index)
+}
@@ -0,0 +1,25 @@
+package routers
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/controllers"
+)
+
+// BudgetRouter is a function to set budget routes on resource path /budgets,
+// the budget controller runs its queries on db
+func BudgetRouter(e *echo.Group, db *sql.DB) {
+
+	// BudgetController is a struct for budget controller
+	budgetController := controllers.NewBudgetController(db)
+
+	// Setting up routes
+	e.GET("", budgetController.Index)
+	e.GET("/:id", budgetController.Show)
+	e.GET("/:id/status", budgetController.Status)
+	e.GET("/:id/alerts", budgetController.Alerts)
+	e.POST("", budgetController.Store)
+	e.PUT("/:id", budgetController.Update)
+	e.DELETE("/:id", budgetController.Delete)
+}
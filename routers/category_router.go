@@ -0,0 +1,23 @@
+package routers
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/controllers"
+)
+
+// CategoryRouter is a function to set category routes on resource path /categories,
+// the category controller runs its queries on db
+func CategoryRouter(e *echo.Group, db *sql.DB) {
+
+	// CategoryController is a struct for category controller
+	categoryController := controllers.NewCategoryController(db)
+
+	// Setting up routes
+	e.GET("", categoryController.Index)
+	e.GET("/:id", categoryController.Show)
+	e.POST("", categoryController.Store)
+	e.PUT("/:id", categoryController.Update)
+	e.DELETE("/:id", categoryController.Delete)
+}
@@ -1,19 +1,52 @@
 package routers
 
 import (
+	"database/sql"
+
 	"github.com/labstack/echo/v4"
 	"github.com/walkmanrd/assessment/controllers"
+	"github.com/walkmanrd/assessment/middlewares"
 )
 
-// ExpenseRouter is a function to set expense routes on resource path /expenses
-func ExpenseRouter(e *echo.Group) {
+// ExpenseRouter is a function to set expense routes on resource path /expenses,
+// the expense controller runs its queries on db
+func ExpenseRouter(e *echo.Group, db *sql.DB) {
 
 	// ExpenseController is a struct for expense controller
-	var expenseController controllers.ExpenseController
+	expenseController := controllers.NewExpenseController(db)
+
+	// AttachmentController is a struct for expense attachment controller
+	attachmentController := controllers.NewAttachmentController(db)
+
+	// AuditController is a struct for expense audit controller
+	auditController := controllers.NewAuditController(db)
+
+	// idempotency and budgetAlerts are middlewares for expense writes
+	idempotency := middlewares.Idempotency(db)
+	budgetAlerts := middlewares.BudgetAlerts(db)
 
 	// Setting up routes
 	e.GET("", expenseController.Index)
+	e.GET("/export", expenseController.Export)
+	e.GET("/search", expenseController.Search)
 	e.GET("/:id", expenseController.Show)
-	e.POST("", expenseController.Store)
-	e.PUT("/:id", expenseController.Update)
+	e.POST("", expenseController.Store, idempotency, budgetAlerts)
+	e.POST("/import", expenseController.Import)
+	e.POST("/batch", expenseController.Batch)
+	e.PUT("/:id", expenseController.Update, budgetAlerts)
+	e.PATCH("/:id", expenseController.Patch, budgetAlerts)
+	e.DELETE("/:id", expenseController.Delete)
+	e.POST("/:id/restore", expenseController.Restore)
+	e.DELETE("/:id/purge", expenseController.Purge)
+	e.POST("/:id/submit", expenseController.Submit)
+	e.POST("/:id/reopen", expenseController.Reopen)
+	e.POST("/:id/approve", expenseController.Approve)
+	e.POST("/:id/reject", expenseController.Reject)
+	e.POST("/:id/reimburse", expenseController.Reimburse)
+	e.GET("/:id/transitions", expenseController.Transitions)
+	e.GET("/:id/history", auditController.History)
+	e.GET("/:id/attachments", attachmentController.Index)
+	e.POST("/:id/attachments", attachmentController.Store)
+	e.GET("/:id/attachments/:attachment_id", attachmentController.Show)
+	e.DELETE("/:id/attachments/:attachment_id", attachmentController.Delete)
 }
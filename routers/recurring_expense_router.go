@@ -0,0 +1,26 @@
+package routers
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/controllers"
+)
+
+// RecurringExpenseRouter is a function to set recurring expense routes on resource path /recurring-expenses,
+// the recurring expense controller runs its queries on db
+func RecurringExpenseRouter(e *echo.Group, db *sql.DB) {
+
+	// RecurringExpenseController is a struct for recurring expense controller
+	recurringExpenseController := controllers.NewRecurringExpenseController(db)
+
+	// Setting up routes
+	e.GET("", recurringExpenseController.Index)
+	e.GET("/:id", recurringExpenseController.Show)
+	e.GET("/:id/preview", recurringExpenseController.Preview)
+	e.POST("", recurringExpenseController.Store)
+	e.PUT("/:id", recurringExpenseController.Update)
+	e.DELETE("/:id", recurringExpenseController.Delete)
+	e.POST("/:id/pause", recurringExpenseController.Pause)
+	e.POST("/:id/resume", recurringExpenseController.Resume)
+}
@@ -0,0 +1,19 @@
+package routers
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/controllers"
+)
+
+// ReportRouter is a function to set report routes on resource path /reports,
+// the report controller runs its queries on db
+func ReportRouter(e *echo.Group, db *sql.DB) {
+
+	// ReportController is a struct for report controller
+	reportController := controllers.NewReportController(db)
+
+	// Setting up routes
+	e.GET("", reportController.Index)
+}
@@ -0,0 +1,21 @@
+package routers
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+	"github.com/walkmanrd/assessment/controllers"
+)
+
+// TagRouter is a function to set tag routes on resource path /tags,
+// the tag controller runs its queries on db
+func TagRouter(e *echo.Group, db *sql.DB) {
+
+	// TagController is a struct for tag controller
+	tagController := controllers.NewTagController(db)
+
+	// Setting up routes
+	e.GET("", tagController.Index)
+	e.PUT("/:name", tagController.Rename)
+	e.POST("/merge", tagController.Merge)
+}
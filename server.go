@@ -2,59 +2,146 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	// "github.com/go-errors/errors"
-	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/walkmanrd/assessment/auth"
 	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/middlewares"
+	"github.com/walkmanrd/assessment/migrations"
+	"github.com/walkmanrd/assessment/repositories"
 	"github.com/walkmanrd/assessment/routers"
-	"github.com/walkmanrd/assessment/types"
+	"github.com/walkmanrd/assessment/services"
 	"github.com/walkmanrd/assessment/validators"
+	"github.com/walkmanrd/assessment/workers"
 
 	_ "github.com/lib/pq"
 )
 
-// init is a function that run before main
-func init() {
+// migrate is a function that run database migrations, as the migrate subcommand or on start
+func migrate(args []string) {
 	db := configs.ConnectDatabase()
-	configs.AutoMigrate(db)
 	defer db.Close()
+
+	if len(args) > 0 {
+		if err := migrations.Command(db, args, os.Stdout); err != nil {
+			log.Fatal("migrate: ", err)
+		}
+		return
+	}
+
+	if !configs.IsMigrateOnStart() {
+		return
+	}
+	if _, err := migrations.MigrateUp(db); err != nil {
+		log.Fatal("can't migrate database: ", err)
+	}
 }
 
-// AuthHeader is a middleware to check authorization header
-func AuthHeader(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		authorization := c.Request().Header.Get("Authorization")
-		authTokenCheck := os.Getenv("AUTH_TOKEN")
+// createUser is a function that run the user subcommand to create a user and print its API key
+func createUser(args []string) {
+	if len(args) < 2 || len(args) > 3 || args[0] != "create" {
+		log.Fatal("usage: user create NAME [ROLE,...]")
+	}
 
-		if authorization == authTokenCheck {
-			return next(c)
-		}
-		return c.JSON(http.StatusUnauthorized, types.Error{Message: "Unauthorized"})
+	roles := []string{auth.RoleEditor}
+	if len(args) == 3 {
+		roles = strings.Split(args[2], ",")
 	}
+
+	db := configs.ConnectDatabase()
+	defer db.Close()
+
+	userService := services.NewUserService(*repositories.NewUserRepository(db), nil)
+	user, key, err := userService.Create(context.Background(), args[1], roles)
+	if err != nil {
+		log.Fatal("can't create user: ", err)
+	}
+
+	fmt.Printf("created user %s (%s) with roles %v, API key: %s\n", user.ID, user.Name, user.Roles, key)
 }
 
-// main is a function that run after init
+// main is a function that run the server or the migrate and user subcommands
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		createUser(os.Args[2:])
+		return
+	}
+	migrate(nil)
+
 	// Echo instance
 	e := echo.New()
-	e.Validator = &validators.CustomValidator{Validator: validator.New()}
+	e.Validator = validators.NewCustomValidator()
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(middleware.RequestID())
 
 	// Routes Public
 	routers.HealthCheckRouter(e)
 
+	// db is a connection pool shared by the requests and the background workers
+	db := configs.ConnectDatabase()
+	defer db.Close()
+
+	// authHeader is a middleware resolving the authorization header of private routes
+	authHeader := middlewares.AuthHeader(db)
+
 	// Routes Private
 	g := e.Group("/expenses")
-	g.Use(AuthHeader)
-	routers.ExpenseRouter(g)
+	g.Use(authHeader, middlewares.Authorize(auth.DefaultPolicy))
+	routers.ExpenseRouter(g, db)
+
+	cg := e.Group("/categories")
+	cg.Use(authHeader, middlewares.Authorize(auth.DefaultPolicy))
+	routers.CategoryRouter(cg, db)
+
+	tg := e.Group("/tags")
+	tg.Use(authHeader, middlewares.Authorize(auth.DefaultPolicy))
+	routers.TagRouter(tg, db)
+
+	rg := e.Group("/reports")
+	rg.Use(authHeader, middlewares.Authorize(auth.DefaultPolicy))
+	routers.ReportRouter(rg, db)
+
+	reg := e.Group("/recurring-expenses")
+	reg.Use(authHeader, middlewares.Authorize(auth.DefaultPolicy))
+	routers.RecurringExpenseRouter(reg, db)
+
+	bg := e.Group("/budgets")
+	bg.Use(authHeader, middlewares.Authorize(auth.DefaultPolicy))
+	routers.BudgetRouter(bg, db)
+
+	ag := e.Group("/audit")
+	ag.Use(authHeader, middlewares.Authorize(auth.DefaultPolicy))
+	routers.AuditRouter(ag, db)
+
+	// Background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	if interval := configs.RecurringExpenseInterval(); interval > 0 {
+		recurringExpenseService := services.NewRecurringExpenseService(*repositories.NewRecurringExpenseRepository(db), *repositories.NewCategoryRepository(db))
+		go workers.NewRecurringExpenseWorker(recurringExpenseService, interval).Run(workerCtx)
+	}
+
+	// Request contexts derive from requestCtx so queries still running when shutdown gives up are canceled
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	e.Server.BaseContext = func(net.Listener) context.Context {
+		return requestCtx
+	}
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -68,9 +155,14 @@ func main() {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt)
 	<-shutdown
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	go func() {
+		<-ctx.Done()
+		cancelRequests()
+	}()
 	if err := e.Shutdown(ctx); err != nil {
 		e.Logger.Fatal(err)
 	}
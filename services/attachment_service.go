@@ -0,0 +1,286 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/storage"
+)
+
+// attachmentContentTypes is a list of receipt content types accepted as sniffed from the content
+var attachmentContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// AttachmentService is a struct for attachment service
+type AttachmentService struct {
+	attachmentRepository repositories.AttachmentRepository
+	expenseRepository    repositories.ExpenseRepository
+	storage              storage.Storage
+}
+
+// NewAttachmentService is a function to create new attachment service, a nil storage uses storage.Default
+func NewAttachmentService(attachmentRepository repositories.AttachmentRepository, expenseRepository repositories.ExpenseRepository, store storage.Storage) *AttachmentService {
+	return &AttachmentService{
+		attachmentRepository: attachmentRepository,
+		expenseRepository:    expenseRepository,
+		storage:              store,
+	}
+}
+
+// Gets is a service function to get the attachments of an owner's expense
+func (c *AttachmentService) Gets(ctx context.Context, ownerID string, expenseID string) ([]models.Attachment, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	if status, err := c.checkExpense(ctx, ownerID, expenseID); err != nil {
+		return nil, status, err
+	}
+
+	attachments, err := c.attachmentRepository.FindAll(ctx, expenseID)
+	if err != nil {
+		return nil, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return attachments, 0, nil
+}
+
+// Create is a service function to attach content to an owner's expense, content the expense already has
+// is not stored twice and is returned with created false
+func (c *AttachmentService) Create(ctx context.Context, ownerID string, expenseID string, filename string, content io.Reader) (models.Attachment, bool, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	if status, err := c.checkEditable(ctx, ownerID, expenseID); err != nil {
+		return models.Attachment{}, false, status, err
+	}
+
+	maxBytes := configs.AttachmentMaxBytes()
+	data, err := io.ReadAll(io.LimitReader(content, maxBytes+1))
+	switch {
+	case err != nil:
+		return models.Attachment{}, false, http.StatusBadRequest, err
+	case len(data) == 0:
+		return models.Attachment{}, false, http.StatusBadRequest, errors.New("attachment is empty")
+	case int64(len(data)) > maxBytes:
+		return models.Attachment{}, false, http.StatusRequestEntityTooLarge, fmt.Errorf("attachment is larger than %d bytes", maxBytes)
+	}
+
+	contentType := strings.TrimSpace(strings.Split(http.DetectContentType(data), ";")[0])
+	if !attachmentContentTypes[contentType] {
+		return models.Attachment{}, false, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported attachment type %s", contentType)
+	}
+
+	store, err := c.store()
+	if err != nil {
+		return models.Attachment{}, false, http.StatusInternalServerError, err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	var attachment models.Attachment
+	var created bool
+	var storeErr error
+	err = c.attachmentRepository.WithChecksumLock(ctx, checksum, func(repository repositories.AttachmentRepository) error {
+		attachment, created, err = repository.Create(ctx, models.Attachment{
+			ExpenseID:   expenseID,
+			Filename:    attachmentFilename(filename),
+			ContentType: contentType,
+			Size:        int64(len(data)),
+			Checksum:    checksum,
+		})
+		if err != nil || !created {
+			return err
+		}
+
+		// the content is stored before the row is committed, a failure rolls the row back
+		storeErr = store.Put(storageKey(checksum), bytes.NewReader(data), attachment.Size, contentType)
+		return storeErr
+	})
+	if storeErr != nil {
+		return models.Attachment{}, false, http.StatusInternalServerError, storeErr
+	}
+	if err != nil {
+		return models.Attachment{}, false, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return attachment, created, 0, nil
+}
+
+// Open is a service function to get an attachment of an owner's expense with its content
+func (c *AttachmentService) Open(ctx context.Context, ownerID string, expenseID string, id string) (models.Attachment, io.ReadCloser, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	if status, err := c.checkExpense(ctx, ownerID, expenseID); err != nil {
+		return models.Attachment{}, nil, status, err
+	}
+
+	attachment, err := c.attachmentRepository.FindOne(ctx, expenseID, id)
+	switch err {
+	case sql.ErrNoRows:
+		return models.Attachment{}, nil, http.StatusNotFound, errors.New("attachment not found")
+	case nil:
+	default:
+		return models.Attachment{}, nil, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	store, err := c.store()
+	if err != nil {
+		return models.Attachment{}, nil, http.StatusInternalServerError, err
+	}
+
+	content, err := store.Get(storageKey(attachment.Checksum))
+	if err != nil {
+		return models.Attachment{}, nil, http.StatusInternalServerError, err
+	}
+
+	return attachment, content, 0, nil
+}
+
+// DeleteById is a service function to delete an attachment of an owner's expense,
+// its content is removed once no attachment refers to it
+func (c *AttachmentService) DeleteById(ctx context.Context, ownerID string, expenseID string, id string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	if status, err := c.checkEditable(ctx, ownerID, expenseID); err != nil {
+		return status, err
+	}
+
+	attachment, err := c.attachmentRepository.FindOne(ctx, expenseID, id)
+	switch err {
+	case sql.ErrNoRows:
+		return http.StatusNotFound, errors.New("attachment not found")
+	case nil:
+	default:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	store, err := c.store()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// the reference check and the removal hold the checksum lock so a concurrent upload can't refer to removed content
+	var storeErr error
+	err = c.attachmentRepository.WithChecksumLock(ctx, attachment.Checksum, func(repository repositories.AttachmentRepository) error {
+		if err := repository.Delete(ctx, expenseID, id); err != nil {
+			return err
+		}
+
+		used, err := repository.IsChecksumUsed(ctx, attachment.Checksum)
+		if err != nil || used {
+			return err
+		}
+
+		storeErr = store.Delete(storageKey(attachment.Checksum))
+		return storeErr
+	})
+	switch {
+	case storeErr != nil:
+		return http.StatusInternalServerError, storeErr
+	case err == sql.ErrNoRows:
+		return http.StatusNotFound, errors.New("attachment not found")
+	case err != nil:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return 0, nil
+}
+
+// removeUnusedContent is a function to remove stored content once no attachment refers to it,
+// the reference check and the removal hold the checksum lock so a concurrent upload can't refer to removed content
+func removeUnusedContent(ctx context.Context, attachmentRepository *repositories.AttachmentRepository, store storage.Storage, checksum string) (int, error) {
+	var storeErr error
+	err := attachmentRepository.WithChecksumLock(ctx, checksum, func(repository repositories.AttachmentRepository) error {
+		used, err := repository.IsChecksumUsed(ctx, checksum)
+		if err != nil || used {
+			return err
+		}
+
+		storeErr = store.Delete(storageKey(checksum))
+		return storeErr
+	})
+	switch {
+	case storeErr != nil:
+		return http.StatusInternalServerError, storeErr
+	case err != nil:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return 0, nil
+}
+
+// checkExpense is a function to make sure an expense exists and belongs to an owner
+func (c *AttachmentService) checkExpense(ctx context.Context, ownerID string, expenseID string) (int, error) {
+	_, status, err := c.findExpense(ctx, ownerID, expenseID)
+	return status, err
+}
+
+// checkEditable is a function to make sure an owner's expense is still a draft its attachments can be changed on
+func (c *AttachmentService) checkEditable(ctx context.Context, ownerID string, expenseID string) (int, error) {
+	expense, status, err := c.findExpense(ctx, ownerID, expenseID)
+	if err != nil {
+		return status, err
+	}
+
+	if expense.Status != models.ExpenseStatusDraft {
+		return http.StatusConflict, fmt.Errorf("expense is %s and can no longer be edited", expense.Status)
+	}
+
+	return 0, nil
+}
+
+// findExpense is a function to get an expense of an owner
+func (c *AttachmentService) findExpense(ctx context.Context, ownerID string, expenseID string) (models.Expense, int, error) {
+	expense, err := c.expenseRepository.FindOne(ctx, ownerID, expenseID)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.Expense{}, http.StatusNotFound, errors.New("expense not found")
+	case nil:
+		return expense, 0, nil
+	default:
+		return models.Expense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// store is a function to get the storage of the service
+func (c *AttachmentService) store() (storage.Storage, error) {
+	if c.storage != nil {
+		return c.storage, nil
+	}
+	return storage.Default()
+}
+
+// storageKey is a function to build the content addressed key of stored content
+func storageKey(checksum string) string {
+	return "sha256/" + checksum[:2] + "/" + checksum
+}
+
+// attachmentFilename is a function to keep only the base name of an uploaded file
+func attachmentFilename(filename string) string {
+	filename = filepath.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if filename == "." || filename == "/" || filename == "" {
+		return "attachment"
+	}
+	return filename
+}
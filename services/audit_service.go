@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// defaultAuditLimit is a number of audit events returned when no limit is given
+const defaultAuditLimit = 50
+
+// AuditService is a struct for audit service
+type AuditService struct {
+	auditRepository repositories.AuditRepository
+}
+
+// NewAuditService is a function to create new audit service
+func NewAuditService(auditRepository repositories.AuditRepository) *AuditService {
+	return &AuditService{
+		auditRepository: auditRepository,
+	}
+}
+
+// Gets is a service function to get a page of audit events matching a query newest first
+func (c *AuditService) Gets(ctx context.Context, query types.AuditQuery) (types.AuditEventList, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+
+	events, err := c.auditRepository.FindAll(ctx, query, limit)
+	if err != nil {
+		return types.AuditEventList{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	total, err := c.auditRepository.Count(ctx, query)
+	if err != nil {
+		return types.AuditEventList{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return types.AuditEventList{Data: events, Total: total}, 0, nil
+}
+
+// History is a service function to get the audit events of an owner's expense newest first,
+// the history outlives the expense so it is also found after a purge
+func (c *AuditService) History(ctx context.Context, ownerID string, id string, query types.AuditQuery) (types.AuditEventList, int, error) {
+	return c.Gets(ctx, types.AuditQuery{
+		Limit:     query.Limit,
+		Offset:    query.Offset,
+		ExpenseID: id,
+		OwnerID:   ownerID,
+		Action:    query.Action,
+	})
+}
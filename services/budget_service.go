@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// BudgetService is a struct for budget service
+type BudgetService struct {
+	budgetRepository   repositories.BudgetRepository
+	categoryRepository repositories.CategoryRepository
+}
+
+// NewBudgetService is a function to create new budget service
+func NewBudgetService(budgetRepository repositories.BudgetRepository, categoryRepository repositories.CategoryRepository) *BudgetService {
+	return &BudgetService{
+		budgetRepository:   budgetRepository,
+		categoryRepository: categoryRepository,
+	}
+}
+
+// Gets is a service function to get an owner's budgets
+func (c *BudgetService) Gets(ctx context.Context, ownerID string) ([]models.Budget, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	budgets, err := c.budgetRepository.FindAll(ctx, ownerID)
+	if err != nil {
+		return nil, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return budgets, 0, nil
+}
+
+// GetById is a service function to get an owner's budget by id
+func (c *BudgetService) GetById(ctx context.Context, ownerID string, id string) (models.Budget, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	budget, err := c.budgetRepository.FindOne(ctx, ownerID, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.Budget{}, http.StatusNotFound, errors.New("budget not found")
+	case nil:
+		return budget, 0, nil
+	default:
+		return models.Budget{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// Create is a service function to create a budget for an owner
+func (c *BudgetService) Create(ctx context.Context, ownerID string, request types.BudgetRequest) (models.Budget, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	budget, status, err := c.prepare(ctx, request)
+	if err != nil {
+		return models.Budget{}, status, err
+	}
+
+	created, err := c.budgetRepository.Create(ctx, ownerID, budget)
+	if err != nil {
+		return models.Budget{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return created, 0, nil
+}
+
+// UpdateById is a service function to update an owner's budget by id
+func (c *BudgetService) UpdateById(ctx context.Context, ownerID string, id string, request types.BudgetRequest) (models.Budget, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	budget, status, err := c.prepare(ctx, request)
+	if err != nil {
+		return models.Budget{}, status, err
+	}
+
+	updated, err := c.budgetRepository.Update(ctx, ownerID, id, budget)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.Budget{}, http.StatusNotFound, errors.New("budget not found")
+	case nil:
+		return updated, 0, nil
+	default:
+		return models.Budget{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// DeleteById is a service function to delete an owner's budget by id
+func (c *BudgetService) DeleteById(ctx context.Context, ownerID string, id string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	err := c.budgetRepository.Delete(ctx, ownerID, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		return http.StatusNotFound, errors.New("budget not found")
+	case nil:
+		return 0, nil
+	default:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// Status is a service function to compute how much of an owner's budget is spent in the period containing a date,
+// an empty date is today
+func (c *BudgetService) Status(ctx context.Context, ownerID string, id string, date string) (types.BudgetStatus, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	budget, status, err := c.GetById(ctx, ownerID, id)
+	if err != nil {
+		return types.BudgetStatus{}, status, err
+	}
+
+	day := today()
+	if date != "" {
+		day, _ = time.Parse(dateLayout, date)
+	}
+
+	budgetStatus, err := c.status(ctx, ownerID, budget, day)
+	if err != nil {
+		return types.BudgetStatus{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return budgetStatus, 0, nil
+}
+
+// Alerts is a service function to get the alerts raised by an owner's budget
+func (c *BudgetService) Alerts(ctx context.Context, ownerID string, id string) ([]models.BudgetAlert, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	if _, status, err := c.GetById(ctx, ownerID, id); err != nil {
+		return nil, status, err
+	}
+
+	alerts, err := c.budgetRepository.FindAlerts(ctx, id)
+	if err != nil {
+		return nil, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return alerts, 0, nil
+}
+
+// CheckExpense is a service function to raise an alert for every threshold a saved expense pushed one of
+// the owner's budgets over in the period of the expense, each threshold alerts once per period
+func (c *BudgetService) CheckExpense(ctx context.Context, ownerID string, expense models.Expense) ([]models.BudgetAlert, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	day, err := time.Parse(dateLayout, expense.SpentAt)
+	if err != nil {
+		return nil, nil
+	}
+
+	budgets, err := c.budgetRepository.FindMatching(ctx, ownerID, expense)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []models.BudgetAlert{}
+	for _, budget := range budgets {
+		budgetStatus, err := c.status(ctx, ownerID, budget, day)
+		if err != nil {
+			return alerts, err
+		}
+
+		for _, threshold := range budgetStatus.Reached {
+			alert, created, err := c.budgetRepository.CreateAlert(ctx, models.BudgetAlert{
+				BudgetID:    budget.ID,
+				PeriodStart: budgetStatus.PeriodStart,
+				Threshold:   int(threshold),
+				Spent:       budgetStatus.Spent,
+				ExpenseID:   &expense.ID,
+			})
+			if err != nil {
+				return alerts, err
+			}
+			if !created {
+				continue
+			}
+
+			log.Printf("budget %s %q reached %d%% (%s of %s %s) for the period from %s\n",
+				budget.ID, budget.Name, threshold, budgetStatus.Spent, budget.Amount, budget.Currency, budgetStatus.PeriodStart)
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts, nil
+}
+
+// status is a function to compute the consumption of a budget in the period containing a day
+func (c *BudgetService) status(ctx context.Context, ownerID string, budget models.Budget, day time.Time) (types.BudgetStatus, error) {
+	start, end := budgetPeriod(budget.Period, day)
+	from, to := start.Format(dateLayout), end.Format(dateLayout)
+
+	spent, count, err := c.budgetRepository.Spent(ctx, ownerID, budget, from, to)
+	if err != nil {
+		return types.BudgetStatus{}, err
+	}
+
+	percent := float64(spent) / float64(budget.Amount) * 100
+	reached := []int64{}
+	for _, threshold := range budget.Thresholds {
+		if percent >= float64(threshold) {
+			reached = append(reached, threshold)
+		}
+	}
+
+	return types.BudgetStatus{
+		BudgetID:    budget.ID,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Amount:      budget.Amount,
+		Spent:       spent,
+		Remaining:   budget.Amount - spent,
+		Percent:     float64(int64(percent*100+0.5)) / 100,
+		Count:       count,
+		Reached:     reached,
+	}, nil
+}
+
+// prepare is a function to check a budget request and turn it into a model
+func (c *BudgetService) prepare(ctx context.Context, request types.BudgetRequest) (models.Budget, int, error) {
+	budget := models.Budget{
+		Name:       request.Name,
+		Amount:     request.Amount,
+		Currency:   currencyOrDefault(request.Currency),
+		Period:     request.Period,
+		CategoryID: request.CategoryID,
+		Thresholds: thresholds(request.Thresholds),
+	}
+
+	if request.Tag != nil {
+		if tag := normalizeTag(*request.Tag); tag != "" {
+			budget.Tag = &tag
+		}
+	}
+	if (budget.Tag == nil) == (budget.CategoryID == nil) {
+		return models.Budget{}, http.StatusBadRequest, errors.New("budget must have either a tag or a category_id")
+	}
+
+	if status, err := categoryExists(ctx, &c.categoryRepository, budget.CategoryID); err != nil {
+		return models.Budget{}, status, err
+	}
+
+	return budget, 0, nil
+}
+
+// thresholds is a function to sort and dedupe alert thresholds falling back to the configured ones
+func thresholds(values []int64) []int64 {
+	if len(values) == 0 {
+		values = configs.BudgetThresholds()
+	}
+
+	sorted := append([]int64{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	unique := make([]int64, 0, len(sorted))
+	for i, value := range sorted {
+		if i == 0 || value != sorted[i-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// budgetPeriod is a function to get the first and last day of the weekly, monthly, quarterly or yearly period
+// containing a day, weeks start on Monday
+func budgetPeriod(period string, day time.Time) (time.Time, time.Time) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case "weekly":
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 6)
+	case "quarterly":
+		start := time.Date(day.Year(), (day.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 3, -1)
+	case "yearly":
+		start := time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, -1)
+	default:
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1)
+	}
+}
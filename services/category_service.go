@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// pqUniqueViolation is a postgres error code for a unique constraint violation
+const pqUniqueViolation = "23505"
+
+// CategoryService is a struct for category service
+type CategoryService struct {
+	categoryRepository repositories.CategoryRepository
+}
+
+// NewCategoryService is a function to create new category service
+func NewCategoryService(categoryRepository repositories.CategoryRepository) *CategoryService {
+	return &CategoryService{
+		categoryRepository: categoryRepository,
+	}
+}
+
+// WithActor is a function to get a copy of the service recording actor on the audit events of its changes
+func (c *CategoryService) WithActor(actor types.Actor) *CategoryService {
+	return &CategoryService{categoryRepository: c.categoryRepository.WithActor(actor)}
+}
+
+// Gets is a service function to get all categories
+func (c *CategoryService) Gets(ctx context.Context) ([]models.Category, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	return c.categoryRepository.FindAll(ctx)
+}
+
+// GetById is a service function to get a category by id
+func (c *CategoryService) GetById(ctx context.Context, id string) (models.Category, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	category, err := c.categoryRepository.FindOne(ctx, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.Category{}, http.StatusNotFound, errors.New("category not found")
+	case nil:
+		return category, 0, nil
+	default:
+		return models.Category{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// Create is a service function to create a new category
+func (c *CategoryService) Create(ctx context.Context, categoryRequest types.CategoryRequest) (models.Category, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	if status, err := c.checkParent(ctx, "", categoryRequest.ParentID); err != nil {
+		return models.Category{}, status, err
+	}
+
+	category, err := c.categoryRepository.Create(ctx, categoryRequest)
+	if err != nil {
+		return models.Category{}, categoryWriteStatus(ctx, err), categoryWriteError(ctx, err)
+	}
+
+	return category, 0, nil
+}
+
+// UpdateById is a service function to rename or move a category by id
+func (c *CategoryService) UpdateById(ctx context.Context, id string, categoryRequest types.CategoryRequest) (models.Category, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	if _, status, err := c.GetById(ctx, id); err != nil {
+		return models.Category{}, status, err
+	}
+
+	if status, err := c.checkParent(ctx, id, categoryRequest.ParentID); err != nil {
+		return models.Category{}, status, err
+	}
+
+	category, err := c.categoryRepository.Update(ctx, id, categoryRequest)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return models.Category{}, http.StatusNotFound, errors.New("category not found")
+	case err != nil:
+		return models.Category{}, categoryWriteStatus(ctx, err), categoryWriteError(ctx, err)
+	}
+
+	return category, 0, nil
+}
+
+// DeleteById is a service function to delete a category without subcategories by id,
+// draft expenses in the category become uncategorized and expenses locked by the approval workflow keep it from being deleted
+func (c *CategoryService) DeleteById(ctx context.Context, id string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	hasChildren, err := c.categoryRepository.HasChildren(ctx, id)
+	if err != nil {
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+	if hasChildren {
+		return http.StatusConflict, errors.New("category has subcategories")
+	}
+
+	err = c.categoryRepository.Delete(ctx, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		return http.StatusNotFound, errors.New("category not found")
+	case repositories.ErrCategoryLocked:
+		return http.StatusConflict, err
+	case nil:
+		return 0, nil
+	default:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// checkParent is a function to make sure a parent category exists and is not the category itself or below it
+func (c *CategoryService) checkParent(ctx context.Context, id string, parentID *string) (int, error) {
+	if parentID == nil {
+		return 0, nil
+	}
+
+	if _, err := c.categoryRepository.FindOne(ctx, *parentID); err == sql.ErrNoRows {
+		return http.StatusBadRequest, errors.New("parent category not found")
+	} else if err != nil {
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	if id == "" {
+		return 0, nil
+	}
+
+	cycle, err := c.categoryRepository.IsDescendant(ctx, id, *parentID)
+	if err != nil {
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+	if cycle {
+		return http.StatusBadRequest, errors.New("category cannot be moved below itself")
+	}
+
+	return 0, nil
+}
+
+// categoryWriteStatus is a function to map a category write error to a status code
+func categoryWriteStatus(ctx context.Context, err error) int {
+	if isUniqueViolation(err) {
+		return http.StatusConflict
+	}
+	return queryStatus(ctx, err)
+}
+
+// categoryWriteError is a function to map a category write error to a client facing error
+func categoryWriteError(ctx context.Context, err error) error {
+	if isUniqueViolation(err) {
+		return errors.New("category already exists")
+	}
+	return queryError(ctx, err)
+}
+
+// isUniqueViolation is a function to check if an error is a postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/walkmanrd/assessment/types"
+)
+
+const (
+	// batchAtomic is a batch mode applying all operations or none
+	batchAtomic = "atomic"
+	// batchBestEffort is a batch mode applying every operation that succeeds
+	batchBestEffort = "best_effort"
+)
+
+// errBatchFailed is an error rolling back an atomic batch after one of its operations failed
+var errBatchFailed = errors.New("batch operation failed")
+
+// Batch is a service function to apply create, update and delete operations on an owner's expenses,
+// an atomic batch runs in one transaction and stops at the first failure leaving the rest unapplied
+func (c *ExpenseService) Batch(ctx context.Context, ownerID string, batchRequest types.ExpenseBatchRequest, validate func(interface{}) error) (types.ExpenseBatchResponse, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opBatch)
+	defer cancel()
+
+	if batchRequest.Mode == "" {
+		batchRequest.Mode = batchAtomic
+	}
+
+	response := types.ExpenseBatchResponse{
+		Mode:    batchRequest.Mode,
+		Results: make([]types.ExpenseBatchResult, len(batchRequest.Operations)),
+	}
+
+	if batchRequest.Mode == batchBestEffort {
+		for i, operation := range batchRequest.Operations {
+			response.Results[i], _ = c.applyOperation(ctx, ownerID, i, operation, validate)
+		}
+		return response, http.StatusMultiStatus, nil
+	}
+
+	status, failed := http.StatusOK, false
+	err := c.WithTx(ctx, func(txService *ExpenseService) error {
+		status, failed = http.StatusOK, false
+
+		for i, operation := range batchRequest.Operations {
+			result, err := txService.applyOperation(ctx, ownerID, i, operation, validate)
+			response.Results[i] = result
+
+			if result.Status >= http.StatusBadRequest {
+				for j := i + 1; j < len(response.Results); j++ {
+					response.Results[j] = types.ExpenseBatchResult{Index: j, Status: http.StatusFailedDependency, Error: "not applied"}
+				}
+				for j := 0; j < i; j++ {
+					response.Results[j] = types.ExpenseBatchResult{Index: j, Status: http.StatusFailedDependency, Error: "rolled back"}
+				}
+
+				// a server error is returned as is so a serialization failure runs the batch again
+				status, failed = http.StatusUnprocessableEntity, true
+				if result.Status >= http.StatusInternalServerError {
+					status = result.Status
+					return err
+				}
+				return errBatchFailed
+			}
+		}
+
+		return nil
+	})
+
+	// a batch whose request ended while it waited to run again reports the timeout or cancellation rather than its last failure
+	if err != nil && (!failed || ctx.Err() != nil) {
+		return types.ExpenseBatchResponse{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return response, status, nil
+}
+
+// applyOperation is a function to apply one batch operation and report its outcome as an HTTP status
+// together with the error of a failed operation
+func (c *ExpenseService) applyOperation(ctx context.Context, ownerID string, index int, operation types.ExpenseBatchOperation, validate func(interface{}) error) (types.ExpenseBatchResult, error) {
+	result := types.ExpenseBatchResult{Index: index}
+
+	fail := func(status int, err error) (types.ExpenseBatchResult, error) {
+		result.Status = status
+		result.Error = err.Error()
+		return result, err
+	}
+
+	if operation.Op != "create" {
+		if _, err := strconv.ParseInt(operation.ID, 10, 64); err != nil {
+			return fail(http.StatusBadRequest, errors.New("invalid parameter id"))
+		}
+	}
+	if operation.Op == "create" || operation.Op == "update" {
+		if operation.Expense == nil {
+			return fail(http.StatusBadRequest, errors.New("expense is required"))
+		}
+		if err := validate(operation.Expense); err != nil {
+			return fail(http.StatusBadRequest, err)
+		}
+	}
+
+	switch operation.Op {
+	case "create":
+		expense, status, err := c.Create(ctx, ownerID, *operation.Expense)
+		if err != nil {
+			return fail(status, err)
+		}
+		result.Status = http.StatusCreated
+		result.Expense = &expense
+	case "update":
+		var versions []int
+		if operation.Version != nil {
+			versions = []int{*operation.Version}
+		}
+
+		expense, status, err := c.UpdateById(ctx, ownerID, operation.ID, *operation.Expense, versions)
+		if err != nil {
+			return fail(status, err)
+		}
+		result.Status = http.StatusOK
+		result.Expense = &expense
+	case "delete":
+		if status, err := c.DeleteById(ctx, ownerID, operation.ID); err != nil {
+			return fail(status, err)
+		}
+		result.Status = http.StatusNoContent
+	default:
+		return fail(http.StatusBadRequest, errors.New("unknown operation "+strconv.Quote(operation.Op)))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// encodeCursor is a function to build an opaque cursor pointing after an expense
+func encodeCursor(query types.ExpenseQuery, expense models.Expense) string {
+	cursor := types.ExpenseCursor{
+		Sort:  query.Sort,
+		Order: query.Order,
+		ID:    expense.ID,
+	}
+
+	switch query.Sort {
+	case "amount":
+		cursor.Value = expense.Amount.String()
+	case "title":
+		cursor.Value = expense.Title
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor is a function to read an opaque cursor
+func decodeCursor(value string) (types.ExpenseCursor, error) {
+	var cursor types.ExpenseCursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return cursor, err
+	}
+
+	if _, err := strconv.ParseInt(cursor.ID, 10, 64); err != nil {
+		return cursor, err
+	}
+
+	return cursor, nil
+}
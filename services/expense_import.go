@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/walkmanrd/assessment/money"
+	"github.com/walkmanrd/assessment/types"
+)
+
+const (
+	// maxImportRows is a number of data rows accepted in one import
+	maxImportRows = 5000
+	// defaultTagDelimiter is a separator of tags inside the tags column
+	defaultTagDelimiter = "|"
+)
+
+// importFields is a list of expense request fields a CSV column can be read into
+var importFields = []string{"title", "amount", "currency", "note", "tags", "category_id", "spent_at"}
+
+// requiredImportFields is a list of fields the CSV header must provide
+var requiredImportFields = []string{"title", "amount", "note", "tags"}
+
+// Import is a service function to create expenses from CSV rows in a single transaction,
+// nothing is created when any row is invalid or when the import is a dry run
+func (c *ExpenseService) Import(ctx context.Context, ownerID string, source io.Reader, query types.ExpenseImportQuery, validate func(interface{}) error) (types.ExpenseImportReport, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opImport)
+	defer cancel()
+
+	reader := csv.NewReader(source)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if query.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(query.Delimiter)
+	}
+
+	tagDelimiter := query.TagDelimiter
+	if tagDelimiter == "" {
+		tagDelimiter = defaultTagDelimiter
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return types.ExpenseImportReport{}, http.StatusBadRequest, fmt.Errorf("invalid csv header: %w", err)
+	}
+
+	columns, err := importColumns(header, query.Map)
+	if err != nil {
+		return types.ExpenseImportReport{}, http.StatusBadRequest, err
+	}
+
+	report := types.ExpenseImportReport{DryRun: query.DryRun, Rows: []types.ExpenseImportRow{}}
+	expenseRequests := []types.ExpenseRequest{}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return types.ExpenseImportReport{}, http.StatusBadRequest, fmt.Errorf("invalid csv: %w", err)
+		}
+		if len(report.Rows) == maxImportRows {
+			return types.ExpenseImportReport{}, http.StatusRequestEntityTooLarge, fmt.Errorf("import is limited to %d rows", maxImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := types.ExpenseImportRow{Row: line}
+
+		expenseRequest, err := importRequest(record, columns, tagDelimiter)
+		if err == nil {
+			err = validate(&expenseRequest)
+		}
+		if err == nil {
+			var status int
+			if status, err = c.prepareCreate(ctx, &expenseRequest); status >= http.StatusInternalServerError {
+				return types.ExpenseImportReport{}, status, err
+			}
+		}
+
+		if err != nil {
+			row.Error = err.Error()
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, row)
+		expenseRequests = append(expenseRequests, expenseRequest)
+	}
+
+	report.Total = len(report.Rows)
+	if report.Total == 0 {
+		return types.ExpenseImportReport{}, http.StatusBadRequest, errors.New("no rows to import")
+	}
+	if report.Failed > 0 {
+		return report, http.StatusUnprocessableEntity, nil
+	}
+
+	expenses, failed, err := c.expenseRepository.CreateMany(ctx, ownerID, expenseRequests, !query.DryRun)
+	if err != nil && failed < 0 {
+		return types.ExpenseImportReport{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+	if err != nil {
+		report.Rows[failed].Error = err.Error()
+		report.Failed = 1
+		return report, http.StatusUnprocessableEntity, nil
+	}
+
+	report.Succeeded = report.Total
+	if query.DryRun {
+		return report, http.StatusOK, nil
+	}
+
+	for i := range report.Rows {
+		report.Rows[i].Expense = &expenses[i]
+	}
+
+	return report, http.StatusCreated, nil
+}
+
+// importColumns is a function to resolve the index of each field in a CSV header,
+// fields are read from the header of the same name unless mapped as field:header
+func importColumns(header []string, mapping []string) (map[string]int, error) {
+	headers := make(map[string]string, len(importFields))
+	for _, field := range importFields {
+		headers[field] = field
+	}
+
+	for _, entry := range mapping {
+		field, name, ok := strings.Cut(entry, ":")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if _, known := headers[field]; !ok || !known {
+			return nil, fmt.Errorf("invalid column mapping %q", entry)
+		}
+		headers[field] = name
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	columns := make(map[string]int, len(importFields))
+	for field, name := range headers {
+		if i, ok := positions[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[field] = i
+		}
+	}
+
+	for _, field := range requiredImportFields {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("missing column %s", headers[field])
+		}
+	}
+
+	return columns, nil
+}
+
+// importRequest is a function to read an expense request from a CSV record
+func importRequest(record []string, columns map[string]int, tagDelimiter string) (types.ExpenseRequest, error) {
+	value := func(field string) string {
+		if i, ok := columns[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	expenseRequest := types.ExpenseRequest{
+		Title:    value("title"),
+		Currency: value("currency"),
+		Note:     value("note"),
+		SpentAt:  value("spent_at"),
+	}
+
+	if tags := value("tags"); tags != "" {
+		expenseRequest.Tags = strings.Split(tags, tagDelimiter)
+	}
+	if categoryID := value("category_id"); categoryID != "" {
+		expenseRequest.CategoryID = &categoryID
+	}
+
+	amount, err := money.ParseAmount(value("amount"))
+	if err != nil {
+		return expenseRequest, fmt.Errorf("invalid amount %q", value("amount"))
+	}
+	expenseRequest.Amount = amount
+
+	return expenseRequest, nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/walkmanrd/assessment/types"
+)
+
+// Search is a service function to find an owner's expenses by the words of their title, note and tags
+func (c *ExpenseService) Search(ctx context.Context, ownerID string, query types.ExpenseSearchQuery) (types.ExpenseSearchResult, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opSearch)
+	defer cancel()
+
+	tsquery := searchTerms(query.Q)
+	if tsquery == "" {
+		return types.ExpenseSearchResult{}, http.StatusBadRequest, errors.New("q must contain a word")
+	}
+	query.Tags = normalizeTags(query.Tags)
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+
+	hits, err := c.expenseRepository.Search(ctx, ownerID, query, tsquery, limit)
+	if err != nil {
+		return types.ExpenseSearchResult{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	total, err := c.expenseRepository.SearchCount(ctx, ownerID, query, tsquery)
+	if err != nil {
+		return types.ExpenseSearchResult{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return types.ExpenseSearchResult{Data: hits, Total: total}, 0, nil
+}
+
+// searchTerms is a function to turn free text into a to_tsquery expression matching all of its words,
+// the last word matches as a prefix so results follow the user while typing
+func searchTerms(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+	return strings.Join(words, " & ")
+}
@@ -1,41 +1,121 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/exports"
 	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/money"
 	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/storage"
 	"github.com/walkmanrd/assessment/types"
 )
 
+// dateLayout is a layout of expense dates
+const dateLayout = "2006-01-02"
+
+// defaultPageLimit is a number of expenses returned when no limit is given
+const defaultPageLimit = 20
+
 // ExpenseService is a struct for expense service
 type ExpenseService struct {
-	expenseRepository repositories.ExpenseRepository
+	expenseRepository    repositories.ExpenseRepository
+	categoryRepository   repositories.CategoryRepository
+	attachmentRepository repositories.AttachmentRepository
+	storage              storage.Storage
 }
 
-// NewExpenseService is a function to create new expense service
-func NewExpenseService(expenseRepository repositories.ExpenseRepository) *ExpenseService {
+// NewExpenseService is a function to create new expense service, a nil storage uses storage.Default
+// for the attachment content of purged expenses
+func NewExpenseService(expenseRepository repositories.ExpenseRepository, categoryRepository repositories.CategoryRepository, attachmentRepository repositories.AttachmentRepository, store storage.Storage) *ExpenseService {
 	return &ExpenseService{
-		expenseRepository: expenseRepository,
+		expenseRepository:    expenseRepository,
+		categoryRepository:   categoryRepository,
+		attachmentRepository: attachmentRepository,
+		storage:              store,
 	}
 }
 
-// GetById is a service function to get an expense by id
-func (c *ExpenseService) Gets() ([]models.Expense, error) {
-	expenses, err := c.expenseRepository.FindAll()
+// WithActor is a function to get a copy of the service recording actor on the audit events of its changes
+func (c *ExpenseService) WithActor(actor types.Actor) *ExpenseService {
+	return &ExpenseService{
+		expenseRepository:    c.expenseRepository.WithActor(actor),
+		categoryRepository:   c.categoryRepository,
+		attachmentRepository: c.attachmentRepository,
+		storage:              c.storage,
+	}
+}
+
+// Gets is a service function to get a page of an owner's expenses
+func (c *ExpenseService) Gets(ctx context.Context, ownerID string, query types.ExpenseQuery) (types.ExpenseList, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
 
+	query.Tags = normalizeTags(query.Tags)
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+
+	var cursor *types.ExpenseCursor
+	if query.Cursor != "" {
+		decoded, err := decodeCursor(query.Cursor)
+		if err != nil || decoded.Sort != query.Sort || decoded.Order != query.Order {
+			return types.ExpenseList{}, http.StatusBadRequest, errors.New("invalid cursor")
+		}
+		cursor = &decoded
+	}
+
+	expenses, err := c.expenseRepository.FindAll(ctx, ownerID, query, cursor, limit+1)
 	if err != nil {
-		return nil, err
+		return types.ExpenseList{}, queryStatus(ctx, err), queryError(ctx, err)
 	}
 
-	return expenses, nil
+	total, err := c.expenseRepository.Count(ctx, ownerID, query)
+	if err != nil {
+		return types.ExpenseList{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	list := types.ExpenseList{Data: expenses, Total: total}
+	if len(expenses) > limit {
+		list.Data = expenses[:limit]
+		list.NextCursor = encodeCursor(query, list.Data[limit-1])
+	}
+
+	return list, 0, nil
+}
+
+// Export is a service function to stream an owner's expenses matching a query into an encoder
+func (c *ExpenseService) Export(ctx context.Context, ownerID string, query types.ExpenseQuery, encoder exports.Encoder) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opExport)
+	defer cancel()
+
+	query.Tags = normalizeTags(query.Tags)
+
+	if err := c.expenseRepository.Each(ctx, ownerID, query, encoder.Encode); err != nil {
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return 0, nil
 }
 
 // GetById is a service function to get an expense by id
-func (c *ExpenseService) GetById(id string) (models.Expense, int, error) {
-	expense, err := c.expenseRepository.FindOne(id)
+func (c *ExpenseService) GetById(ctx context.Context, ownerID string, id string) (models.Expense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	expense, err := c.expenseRepository.FindOne(ctx, ownerID, id)
 
 	switch err {
 	case sql.ErrNoRows:
@@ -43,28 +123,203 @@ func (c *ExpenseService) GetById(id string) (models.Expense, int, error) {
 	case nil:
 		return expense, 0, nil
 	default:
-		return models.Expense{}, http.StatusInternalServerError, err
+		return models.Expense{}, queryStatus(ctx, err), queryError(ctx, err)
 	}
 }
 
 // Create is a service function to create a new expense
-func (c *ExpenseService) Create(expenseRequest types.ExpenseRequest) (models.Expense, error) {
-	expense, err := c.expenseRepository.Create(expenseRequest)
+func (c *ExpenseService) Create(ctx context.Context, ownerID string, expenseRequest types.ExpenseRequest) (models.Expense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	if status, err := c.prepareCreate(ctx, &expenseRequest); err != nil {
+		return models.Expense{}, status, err
+	}
+
+	expense, err := c.expenseRepository.Create(ctx, ownerID, expenseRequest)
+
+	if err != nil {
+		return models.Expense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return expense, 0, nil
+}
+
+// UpdateById is a service function to update an expense by id when its version is one of versions,
+// a nil versions updates regardless of the current version
+func (c *ExpenseService) UpdateById(ctx context.Context, ownerID string, id string, expenseRequest types.ExpenseRequest, versions []int) (models.Expense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	if expenseRequest.Tags = normalizeTags(expenseRequest.Tags); len(expenseRequest.Tags) == 0 {
+		return models.Expense{}, http.StatusBadRequest, errors.New("tags must not be blank")
+	}
+
+	if status, err := c.checkCategory(ctx, expenseRequest.CategoryID); err != nil {
+		return models.Expense{}, status, err
+	}
+
+	expenseRequest.Currency = currencyOrDefault(expenseRequest.Currency)
+	expense, err := c.expenseRepository.Update(ctx, ownerID, id, expenseRequest, versions)
+
+	switch err {
+	case sql.ErrNoRows:
+		if status, err := c.checkEditable(ctx, ownerID, id); err != nil {
+			return models.Expense{}, status, err
+		}
+		if versions == nil {
+			return models.Expense{}, http.StatusNotFound, errors.New("expense not found")
+		}
+		return models.Expense{}, http.StatusPreconditionFailed, errors.New("expense has been modified")
+	case nil:
+		return expense, 0, nil
+	default:
+		return models.Expense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// DeleteById is a service function to soft delete an expense by id
+func (c *ExpenseService) DeleteById(ctx context.Context, ownerID string, id string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	err := c.expenseRepository.Delete(ctx, ownerID, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		if status, err := c.checkEditable(ctx, ownerID, id); err != nil {
+			return status, err
+		}
+		return http.StatusNotFound, errors.New("expense not found")
+	case nil:
+		return 0, nil
+	default:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// RestoreById is a service function to restore a soft deleted expense by id
+func (c *ExpenseService) RestoreById(ctx context.Context, ownerID string, id string) (models.Expense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	expense, err := c.expenseRepository.Restore(ctx, ownerID, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.Expense{}, http.StatusNotFound, errors.New("deleted expense not found")
+	case nil:
+		return expense, 0, nil
+	default:
+		return models.Expense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
 
+// PurgeById is a service function to permanently delete an expense of any owner by id,
+// the content of its attachments is removed once no other attachment refers to it
+func (c *ExpenseService) PurgeById(ctx context.Context, id string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	// the attachment rows go with the expense, their checksums are collected before
+	attachments, err := c.attachmentRepository.FindAll(ctx, id)
 	if err != nil {
-		return models.Expense{}, err
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	var store storage.Storage
+	if len(attachments) > 0 {
+		if store, err = c.store(); err != nil {
+			return http.StatusInternalServerError, err
+		}
 	}
 
-	return expense, nil
+	err = c.expenseRepository.Purge(ctx, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		return http.StatusNotFound, errors.New("expense not found")
+	case nil:
+	default:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	for _, attachment := range attachments {
+		if status, err := removeUnusedContent(ctx, &c.attachmentRepository, store, attachment.Checksum); err != nil {
+			return status, err
+		}
+	}
+
+	return 0, nil
 }
 
-// UpdateById is a service function to update an expense by id
-func (c *ExpenseService) UpdateById(id string, expenseRequest types.ExpenseRequest) (models.Expense, error) {
-	expense, err := c.expenseRepository.Update(id, expenseRequest)
+// store is a function to get the storage of the service
+func (c *ExpenseService) store() (storage.Storage, error) {
+	if c.storage != nil {
+		return c.storage, nil
+	}
+	return storage.Default()
+}
 
+// checkEditable is a function to make sure an owner's expense exists and is still a draft
+func (c *ExpenseService) checkEditable(ctx context.Context, ownerID string, id string) (int, error) {
+	expense, status, err := c.GetById(ctx, ownerID, id)
 	if err != nil {
-		return models.Expense{}, err
+		return status, err
+	}
+
+	if expense.Status != models.ExpenseStatusDraft {
+		return http.StatusConflict, fmt.Errorf("expense is %s and can no longer be edited", expense.Status)
+	}
+
+	return 0, nil
+}
+
+// prepareCreate is a function to normalize a new expense and fill in its defaults
+func (c *ExpenseService) prepareCreate(ctx context.Context, expenseRequest *types.ExpenseRequest) (int, error) {
+	if expenseRequest.Tags = normalizeTags(expenseRequest.Tags); len(expenseRequest.Tags) == 0 {
+		return http.StatusBadRequest, errors.New("tags must not be blank")
+	}
+
+	if status, err := c.checkCategory(ctx, expenseRequest.CategoryID); err != nil {
+		return status, err
+	}
+
+	expenseRequest.Currency = currencyOrDefault(expenseRequest.Currency)
+	if expenseRequest.SpentAt == "" {
+		expenseRequest.SpentAt = time.Now().In(configs.Location()).Format(dateLayout)
+	}
+
+	return 0, nil
+}
+
+// checkCategory is a function to make sure an optional category of an expense exists
+func (c *ExpenseService) checkCategory(ctx context.Context, categoryID *string) (int, error) {
+	return categoryExists(ctx, &c.categoryRepository, categoryID)
+}
+
+// categoryExists is a function to make sure an optional category exists
+func categoryExists(ctx context.Context, categoryRepository *repositories.CategoryRepository, categoryID *string) (int, error) {
+	if categoryID == nil {
+		return 0, nil
 	}
 
-	return expense, nil
+	_, err := categoryRepository.FindOne(ctx, *categoryID)
+
+	switch err {
+	case sql.ErrNoRows:
+		return http.StatusBadRequest, errors.New("category not found")
+	case nil:
+		return 0, nil
+	default:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// currencyOrDefault is a function to normalize a currency code falling back to the default currency
+func currencyOrDefault(currency string) string {
+	if currency = money.NormalizeCurrency(currency); currency == "" {
+		return configs.DefaultCurrency()
+	}
+	return currency
 }
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/types"
+)
+
+const (
+	// ActionSubmit is an action of an owner sending a draft expense for approval
+	ActionSubmit = "submit"
+	// ActionReopen is an action of an owner turning a rejected expense back into a draft
+	ActionReopen = "reopen"
+	// ActionApprove is an action of a reviewer approving a submitted expense
+	ActionApprove = "approve"
+	// ActionReject is an action of a reviewer sending a submitted expense back to its owner
+	ActionReject = "reject"
+	// ActionReimburse is an action of a reviewer marking an approved expense as paid back
+	ActionReimburse = "reimburse"
+)
+
+// expenseTransition is a struct for the status change an action makes and who may make it
+type expenseTransition struct {
+	from            string
+	to              string
+	review          bool
+	commentRequired bool
+}
+
+// expenseTransitions is a state machine of the expense approval workflow keyed by action
+var expenseTransitions = map[string]expenseTransition{
+	ActionSubmit:    {from: models.ExpenseStatusDraft, to: models.ExpenseStatusSubmitted},
+	ActionReopen:    {from: models.ExpenseStatusRejected, to: models.ExpenseStatusDraft},
+	ActionApprove:   {from: models.ExpenseStatusSubmitted, to: models.ExpenseStatusApproved, review: true},
+	ActionReject:    {from: models.ExpenseStatusSubmitted, to: models.ExpenseStatusRejected, review: true, commentRequired: true},
+	ActionReimburse: {from: models.ExpenseStatusApproved, to: models.ExpenseStatusReimbursed, review: true},
+}
+
+// Transition is a service function to move an expense through the approval workflow, owners submit and reopen
+// their own expenses while reviewers approve, reject and reimburse expenses of other owners
+func (c *ExpenseService) Transition(ctx context.Context, actorID string, canReview bool, id string, action string, transitionRequest types.ExpenseTransitionRequest) (models.Expense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	transition, ok := expenseTransitions[action]
+	if !ok {
+		return models.Expense{}, http.StatusInternalServerError, fmt.Errorf("unknown expense action %q", action)
+	}
+
+	ownerID, expense, status, err := c.findVisible(ctx, actorID, canReview, id)
+	if err != nil {
+		return models.Expense{}, status, err
+	}
+
+	if transition.review && ownerID == actorID {
+		return models.Expense{}, http.StatusForbidden, fmt.Errorf("can't %s your own expense", action)
+	}
+	if !transition.review && ownerID != actorID {
+		return models.Expense{}, http.StatusForbidden, fmt.Errorf("only the owner can %s an expense", action)
+	}
+
+	comment := strings.TrimSpace(transitionRequest.Comment)
+	if transition.commentRequired && comment == "" {
+		return models.Expense{}, http.StatusBadRequest, fmt.Errorf("comment is required to %s an expense", action)
+	}
+
+	if expense.Status != transition.from {
+		return models.Expense{}, http.StatusConflict, fmt.Errorf("can't %s an expense that is %s", action, expense.Status)
+	}
+
+	expense, err = c.expenseRepository.Transition(ctx, id, transition.from, transition.to, actorID, comment)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.Expense{}, http.StatusConflict, errors.New("expense was changed by another request")
+	case nil:
+		return expense, 0, nil
+	default:
+		return models.Expense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// Transitions is a service function to get the status history of an expense
+func (c *ExpenseService) Transitions(ctx context.Context, actorID string, canReview bool, id string) ([]models.ExpenseTransition, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	if _, _, status, err := c.findVisible(ctx, actorID, canReview, id); err != nil {
+		return nil, status, err
+	}
+
+	transitions, err := c.expenseRepository.FindTransitions(ctx, id)
+	if err != nil {
+		return nil, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return transitions, 0, nil
+}
+
+// findVisible is a function to get an expense with its owner id when the actor owns it or may review it
+func (c *ExpenseService) findVisible(ctx context.Context, actorID string, canReview bool, id string) (string, models.Expense, int, error) {
+	ownerID, expense, err := c.expenseRepository.FindWithOwner(ctx, id)
+
+	switch {
+	case err == sql.ErrNoRows, err == nil && ownerID != actorID && !canReview:
+		return "", models.Expense{}, http.StatusNotFound, errors.New("expense not found")
+	case err != nil:
+		return "", models.Expense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return ownerID, expense, 0, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+)
+
+// idempotencyPurgeInterval is a minimum time between deletions of expired idempotency keys
+const idempotencyPurgeInterval = time.Hour
+
+var (
+	// ErrIdempotencyKeyInFlight is an error for a key whose original request has not finished
+	ErrIdempotencyKeyInFlight = errors.New("a request with this Idempotency-Key is in progress")
+	// ErrIdempotencyKeyMismatch is an error for a key reused with a different request
+	ErrIdempotencyKeyMismatch = errors.New("Idempotency-Key was already used with a different request")
+)
+
+// IdempotencyService is a struct for idempotency service
+type IdempotencyService struct {
+	idempotencyKeyRepository repositories.IdempotencyKeyRepository
+	lastPurge                int64
+}
+
+// NewIdempotencyService is a function to create new idempotency service
+func NewIdempotencyService(idempotencyKeyRepository repositories.IdempotencyKeyRepository) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyKeyRepository: idempotencyKeyRepository,
+	}
+}
+
+// Begin is a service function to claim a key for a request for a short lease, when the key is already used
+// it returns the recorded response to replay or an error when the request differs or is in flight
+func (c *IdempotencyService) Begin(ctx context.Context, ownerID string, key string, requestHash string) (models.IdempotencyKey, bool, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	c.purgeExpired(ctx)
+
+	// a key expiring between the claim and the lookup is claimed again
+	for attempt := 0; attempt < 2; attempt++ {
+		claimed, err := c.idempotencyKeyRepository.Claim(ctx, ownerID, key, requestHash, configs.IdempotencyLease())
+		if err != nil {
+			return models.IdempotencyKey{}, false, err
+		}
+		if claimed {
+			return models.IdempotencyKey{OwnerID: ownerID, Key: key, RequestHash: requestHash}, true, nil
+		}
+
+		record, err := c.idempotencyKeyRepository.FindOne(ctx, ownerID, key)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return models.IdempotencyKey{}, false, err
+		}
+
+		switch {
+		case record.RequestHash != requestHash:
+			return models.IdempotencyKey{}, false, ErrIdempotencyKeyMismatch
+		case record.StatusCode == nil:
+			return models.IdempotencyKey{}, false, ErrIdempotencyKeyInFlight
+		default:
+			return record, false, nil
+		}
+	}
+
+	return models.IdempotencyKey{}, false, ErrIdempotencyKeyInFlight
+}
+
+// Complete is a service function to record the response of a claimed key for replay until the key expires
+func (c *IdempotencyService) Complete(ctx context.Context, record models.IdempotencyKey) error {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	return c.idempotencyKeyRepository.Complete(ctx, record, configs.IdempotencyTTL())
+}
+
+// Hold is a service function to renew the lease of a claimed key every third of the lease until the returned
+// function is called, so a request running longer than the lease keeps its key, the function waits for
+// a renewal in progress so none lands after the key is completed or released
+func (c *IdempotencyService) Hold(record models.IdempotencyKey) func() {
+	lease := configs.IdempotencyLease()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := withQueryTimeout(context.Background(), opWrite)
+			err := c.idempotencyKeyRepository.Renew(ctx, record.OwnerID, record.Key, record.RequestHash, lease)
+			cancel()
+			if err != nil {
+				log.Println("can't renew idempotency key", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// Release is a service function to give up a claimed key so the request can be retried
+func (c *IdempotencyService) Release(ctx context.Context, record models.IdempotencyKey) error {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	return c.idempotencyKeyRepository.Release(ctx, record.OwnerID, record.Key, record.RequestHash)
+}
+
+// purgeExpired is a function to delete expired keys at most once per purge interval
+func (c *IdempotencyService) purgeExpired(ctx context.Context) {
+	now := time.Now().Unix()
+	last := atomic.LoadInt64(&c.lastPurge)
+
+	if now-last < int64(idempotencyPurgeInterval/time.Second) || !atomic.CompareAndSwapInt64(&c.lastPurge, last, now) {
+		return
+	}
+
+	// expired keys are also taken over on claim so a failed purge is only retried later
+	_ = c.idempotencyKeyRepository.DeleteExpired(ctx)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/walkmanrd/assessment/configs"
+)
+
+// StatusClientClosedRequest is a status of a request its client went away from before it was answered
+const StatusClientClosedRequest = 499
+
+const (
+	// opRead is an operation getting expenses
+	opRead = "read"
+	// opWrite is an operation changing an expense
+	opWrite = "write"
+	// opSearch is an operation searching expenses
+	opSearch = "search"
+	// opExport is an operation streaming expenses to a file, it has no timeout unless one is set for it
+	opExport = "export"
+	// opImport is an operation creating expenses from a file
+	opImport = "import"
+	// opBatch is an operation applying a batch of changes
+	opBatch = "batch"
+)
+
+// withQueryTimeout is a function to bound the queries of an operation by its configured timeout
+func withQueryTimeout(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	if timeout := configs.QueryTimeout(operation); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// queryStatus is a function to get the HTTP status of a failed query,
+// a query cut off by its timeout or canceled by its client is not a server error
+func queryStatus(ctx context.Context, err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled), errors.Is(ctx.Err(), context.Canceled):
+		return StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// queryError is a function to describe a failed query, the driver error of a canceled query is replaced by its cause
+func queryError(ctx context.Context, err error) error {
+	switch queryStatus(ctx, err) {
+	case http.StatusGatewayTimeout:
+		return errors.New("query timed out")
+	case StatusClientClosedRequest:
+		return errors.New("request canceled")
+	default:
+		return err
+	}
+}
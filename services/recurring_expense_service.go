@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/recurrence"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/types"
+)
+
+const (
+	// defaultPreviewCount is a number of occurrences previewed when no count is given
+	defaultPreviewCount = 5
+	// materializeBatchSize is a maximum number of occurrences of one schedule materialized per transaction
+	materializeBatchSize = 500
+	// materializeRetryDelay is a delay before a schedule that failed to materialize is claimed again
+	materializeRetryDelay = time.Minute
+	// materializeMaxRetryDelay is a maximum delay between attempts of a schedule that keeps failing
+	materializeMaxRetryDelay = time.Hour
+)
+
+// RecurringExpenseService is a struct for recurring expense service
+type RecurringExpenseService struct {
+	recurringExpenseRepository repositories.RecurringExpenseRepository
+	categoryRepository         repositories.CategoryRepository
+}
+
+// NewRecurringExpenseService is a function to create new recurring expense service
+func NewRecurringExpenseService(recurringExpenseRepository repositories.RecurringExpenseRepository, categoryRepository repositories.CategoryRepository) *RecurringExpenseService {
+	return &RecurringExpenseService{
+		recurringExpenseRepository: recurringExpenseRepository,
+		categoryRepository:         categoryRepository,
+	}
+}
+
+// Gets is a service function to get an owner's recurring expenses
+func (c *RecurringExpenseService) Gets(ctx context.Context, ownerID string) ([]models.RecurringExpense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	recurringExpenses, err := c.recurringExpenseRepository.FindAll(ctx, ownerID)
+	if err != nil {
+		return nil, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return recurringExpenses, 0, nil
+}
+
+// GetById is a service function to get an owner's recurring expense by id
+func (c *RecurringExpenseService) GetById(ctx context.Context, ownerID string, id string) (models.RecurringExpense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	recurringExpense, err := c.recurringExpenseRepository.FindOne(ctx, ownerID, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.RecurringExpense{}, http.StatusNotFound, errors.New("recurring expense not found")
+	case nil:
+		return recurringExpense, 0, nil
+	default:
+		return models.RecurringExpense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// Create is a service function to create a recurring expense for an owner, occurrences since its start
+// date are materialized by the worker so a schedule may be entered after the fact
+func (c *RecurringExpenseService) Create(ctx context.Context, ownerID string, request types.RecurringExpenseRequest) (models.RecurringExpense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	recurringExpense, status, err := c.prepare(ctx, request)
+	if err != nil {
+		return models.RecurringExpense{}, status, err
+	}
+
+	recurringExpense.Position, recurringExpense.NextOn = position(schedule(recurringExpense), time.Time{})
+
+	created, err := c.recurringExpenseRepository.Create(ctx, ownerID, recurringExpense)
+	if err != nil {
+		return models.RecurringExpense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return created, 0, nil
+}
+
+// UpdateById is a service function to update an owner's recurring expense by id, the updated schedule
+// continues from today without materializing occurrences it would have had before
+func (c *RecurringExpenseService) UpdateById(ctx context.Context, ownerID string, id string, request types.RecurringExpenseRequest) (models.RecurringExpense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	recurringExpense, status, err := c.prepare(ctx, request)
+	if err != nil {
+		return models.RecurringExpense{}, status, err
+	}
+
+	recurringExpense.Position, recurringExpense.NextOn = position(schedule(recurringExpense), today())
+
+	updated, err := c.recurringExpenseRepository.Update(ctx, ownerID, id, recurringExpense)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.RecurringExpense{}, http.StatusNotFound, errors.New("recurring expense not found")
+	case nil:
+		return updated, 0, nil
+	default:
+		return models.RecurringExpense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// DeleteById is a service function to delete an owner's recurring expense by id
+func (c *RecurringExpenseService) DeleteById(ctx context.Context, ownerID string, id string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	err := c.recurringExpenseRepository.Delete(ctx, ownerID, id)
+
+	switch err {
+	case sql.ErrNoRows:
+		return http.StatusNotFound, errors.New("recurring expense not found")
+	case nil:
+		return 0, nil
+	default:
+		return queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// Preview is a service function to list the next occurrence dates of an owner's recurring expense
+func (c *RecurringExpenseService) Preview(ctx context.Context, ownerID string, id string, count int) (types.RecurringExpensePreview, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	recurringExpense, status, err := c.GetById(ctx, ownerID, id)
+	if err != nil {
+		return types.RecurringExpensePreview{}, status, err
+	}
+
+	if count == 0 {
+		count = defaultPreviewCount
+	}
+
+	preview := types.RecurringExpensePreview{Dates: []string{}}
+	for _, date := range schedule(recurringExpense).Next(recurringExpense.Position, count) {
+		preview.Dates = append(preview.Dates, date.Format(recurrence.DateLayout))
+	}
+
+	return preview, 0, nil
+}
+
+// Pause is a service function to stop materializing an owner's recurring expense
+func (c *RecurringExpenseService) Pause(ctx context.Context, ownerID string, id string) (models.RecurringExpense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	recurringExpense, status, err := c.GetById(ctx, ownerID, id)
+	if err != nil {
+		return models.RecurringExpense{}, status, err
+	}
+
+	return c.setPaused(ctx, ownerID, id, true, recurringExpense.Position, recurringExpense.NextOn)
+}
+
+// Resume is a service function to materialize an owner's paused recurring expense again,
+// occurrences that fell due while it was paused are skipped
+func (c *RecurringExpenseService) Resume(ctx context.Context, ownerID string, id string) (models.RecurringExpense, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	recurringExpense, status, err := c.GetById(ctx, ownerID, id)
+	if err != nil {
+		return models.RecurringExpense{}, status, err
+	}
+
+	if !recurringExpense.Paused {
+		return recurringExpense, 0, nil
+	}
+
+	next, nextOn := position(schedule(recurringExpense), today())
+	if next < recurringExpense.Position {
+		next, nextOn = recurringExpense.Position, recurringExpense.NextOn
+	}
+
+	return c.setPaused(ctx, ownerID, id, false, next, nextOn)
+}
+
+// MaterializeDue is a service function to create the expenses of every occurrence due by now, one schedule
+// per transaction, it is safe to run on many replicas at once and returns how many expenses were created,
+// a schedule that fails is set aside to be retried later so it doesn't hold up the others
+func (c *RecurringExpenseService) MaterializeDue(ctx context.Context, now time.Time) (int, error) {
+	date := recurrence.Truncate(now.In(configs.Location()))
+	created, failed := 0, 0
+	var firstErr error
+
+	for {
+		count, id, err := c.materializeNext(ctx, date)
+		created += count
+
+		switch {
+		case err != nil && id == "":
+			return created, err
+		case err != nil:
+			if err := c.recurringExpenseRepository.Fail(ctx, id, materializeRetryDelay, materializeMaxRetryDelay); err != nil {
+				return created, err
+			}
+			if failed++; firstErr == nil {
+				firstErr = fmt.Errorf("recurring expense %s: %w", id, err)
+			}
+		case id == "":
+			if failed > 0 {
+				return created, fmt.Errorf("%d recurring expenses failed to materialize, first %w", failed, firstErr)
+			}
+			return created, nil
+		}
+	}
+}
+
+// errNothingDue is an error rolling back the transaction of materializeNext when no schedule is due
+var errNothingDue = errors.New("no recurring expense is due")
+
+// materializeNext is a function to materialize the occurrences due by a date of one schedule, it returns the id
+// of the schedule it claimed also when materializing it failed, an empty id is returned when no schedule is due
+func (c *RecurringExpenseService) materializeNext(ctx context.Context, date time.Time) (int, string, error) {
+	created, id := 0, ""
+
+	begin := func() (*sql.Tx, error) {
+		return c.recurringExpenseRepository.Begin(ctx)
+	}
+
+	err := runInTx(ctx, begin, func(tx *sql.Tx) error {
+		created, id = 0, ""
+		repository := c.recurringExpenseRepository.WithTx(tx)
+
+		recurringExpense, err := repository.ClaimDue(ctx, date.Format(recurrence.DateLayout))
+		if err == sql.ErrNoRows {
+			return errNothingDue
+		}
+		if err != nil {
+			return err
+		}
+		id = recurringExpense.ID
+
+		rule := schedule(recurringExpense)
+		next := recurringExpense.Position
+
+		for i := 0; i < materializeBatchSize; i++ {
+			occurrence, ok := rule.At(next)
+			if !ok || occurrence.After(date) {
+				break
+			}
+
+			inserted, err := repository.Materialize(ctx, recurringExpense.ID, occurrence.Format(recurrence.DateLayout))
+			if err != nil {
+				return err
+			}
+			if inserted {
+				created++
+			}
+			next++
+		}
+
+		return repository.Advance(ctx, recurringExpense.ID, next, occurrenceDate(rule, next))
+	})
+
+	switch err {
+	case errNothingDue:
+		return 0, "", nil
+	case nil:
+		return created, id, nil
+	default:
+		return 0, id, err
+	}
+}
+
+// setPaused is a function to pause or resume a recurring expense from a position
+func (c *RecurringExpenseService) setPaused(ctx context.Context, ownerID string, id string, paused bool, next int, nextOn *string) (models.RecurringExpense, int, error) {
+	recurringExpense, err := c.recurringExpenseRepository.SetPaused(ctx, ownerID, id, paused, next, nextOn)
+
+	switch err {
+	case sql.ErrNoRows:
+		return models.RecurringExpense{}, http.StatusNotFound, errors.New("recurring expense not found")
+	case nil:
+		return recurringExpense, 0, nil
+	default:
+		return models.RecurringExpense{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+}
+
+// prepare is a function to check a recurring expense request and turn it into a model
+func (c *RecurringExpenseService) prepare(ctx context.Context, request types.RecurringExpenseRequest) (models.RecurringExpense, int, error) {
+	recurringExpense := models.RecurringExpense{
+		Title:      request.Title,
+		Amount:     request.Amount,
+		Currency:   currencyOrDefault(request.Currency),
+		Note:       request.Note,
+		Tags:       normalizeTags(request.Tags),
+		CategoryID: request.CategoryID,
+		Frequency:  request.Frequency,
+		Interval:   request.Interval,
+		StartsOn:   request.StartsOn,
+		EndsOn:     request.EndsOn,
+		Count:      request.Count,
+	}
+
+	if len(recurringExpense.Tags) == 0 {
+		return models.RecurringExpense{}, http.StatusBadRequest, errors.New("tags must not be blank")
+	}
+	if recurringExpense.Interval == 0 {
+		recurringExpense.Interval = 1
+	}
+	if recurringExpense.EndsOn != nil && *recurringExpense.EndsOn < recurringExpense.StartsOn {
+		return models.RecurringExpense{}, http.StatusBadRequest, errors.New("ends_on must not be before starts_on")
+	}
+
+	if status, err := categoryExists(ctx, &c.categoryRepository, recurringExpense.CategoryID); err != nil {
+		return models.RecurringExpense{}, status, err
+	}
+
+	return recurringExpense, 0, nil
+}
+
+// schedule is a function to get the recurrence rule of a recurring expense
+func schedule(recurringExpense models.RecurringExpense) recurrence.Rule {
+	rule := recurrence.Rule{Frequency: recurringExpense.Frequency, Interval: recurringExpense.Interval}
+	rule.Start, _ = time.Parse(recurrence.DateLayout, recurringExpense.StartsOn)
+	if recurringExpense.EndsOn != nil {
+		rule.Until, _ = time.Parse(recurrence.DateLayout, *recurringExpense.EndsOn)
+	}
+	if recurringExpense.Count != nil {
+		rule.Count = *recurringExpense.Count
+	}
+	return rule
+}
+
+// position is a function to get the index and date of the first occurrence of a rule on or after a date
+func position(rule recurrence.Rule, from time.Time) (int, *string) {
+	next := rule.Seek(from)
+	return next, occurrenceDate(rule, next)
+}
+
+// occurrenceDate is a function to get the formatted date of the nth occurrence of a rule or nil when the rule ends before
+func occurrenceDate(rule recurrence.Rule, n int) *string {
+	occurrence, ok := rule.At(n)
+	if !ok {
+		return nil
+	}
+	date := occurrence.Format(recurrence.DateLayout)
+	return &date
+}
+
+// today is a function to get the current date in the application time zone
+func today() time.Time {
+	return recurrence.Truncate(time.Now().In(configs.Location()))
+}
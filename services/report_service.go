@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// ReportService is a struct for report service
+type ReportService struct {
+	reportRepository repositories.ReportRepository
+}
+
+// NewReportService is a function to create new report service
+func NewReportService(reportRepository repositories.ReportRepository) *ReportService {
+	return &ReportService{
+		reportRepository: reportRepository,
+	}
+}
+
+// Summarize is a service function to aggregate an owner's expenses grouped by a dimension
+func (c *ReportService) Summarize(ctx context.Context, ownerID string, query types.ReportQuery) (types.Report, int, error) {
+	// dates are formatted as YYYY-MM-DD so they compare in calendar order
+	if query.From != "" && query.To != "" && query.From > query.To {
+		return types.Report{}, http.StatusBadRequest, errors.New("from must not be after to")
+	}
+
+	query.Tags = normalizeTags(query.Tags)
+
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	groups, err := c.reportRepository.Summarize(ctx, ownerID, query)
+	if err != nil {
+		return types.Report{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+
+	return types.Report{GroupBy: query.GroupBy, From: query.From, To: query.To, Groups: groups}, 0, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/types"
+)
+
+// TagService is a struct for tag service
+type TagService struct {
+	tagRepository repositories.TagRepository
+}
+
+// NewTagService is a function to create new tag service
+func NewTagService(tagRepository repositories.TagRepository) *TagService {
+	return &TagService{
+		tagRepository: tagRepository,
+	}
+}
+
+// WithActor is a function to get a copy of the service recording actor on the audit events of its changes
+func (c *TagService) WithActor(actor types.Actor) *TagService {
+	return &TagService{tagRepository: c.tagRepository.WithActor(actor)}
+}
+
+// Gets is a service function to get an owner's tags with usage counts
+func (c *TagService) Gets(ctx context.Context, ownerID string) ([]models.Tag, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	return c.tagRepository.FindAll(ctx, ownerID)
+}
+
+// Rename is a service function to rename a tag across all of an owner's expenses
+func (c *TagService) Rename(ctx context.Context, ownerID string, name string, renameRequest types.TagRenameRequest) (types.TagChange, int, error) {
+	return c.Merge(ctx, ownerID, types.TagMergeRequest{Sources: []string{name}, Target: renameRequest.Name})
+}
+
+// Merge is a service function to fold several tags into one across all of an owner's draft expenses
+func (c *TagService) Merge(ctx context.Context, ownerID string, mergeRequest types.TagMergeRequest) (types.TagChange, int, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	sources := normalizeTags(mergeRequest.Sources)
+	target := normalizeTag(mergeRequest.Target)
+
+	if len(sources) == 0 || target == "" {
+		return types.TagChange{}, http.StatusBadRequest, errors.New("tag must not be blank")
+	}
+
+	updated, err := c.tagRepository.Replace(ctx, ownerID, sources, target)
+	if err != nil {
+		return types.TagChange{}, queryStatus(ctx, err), queryError(ctx, err)
+	}
+	if updated == 0 {
+		// the tags are listed on locked expenses too, those are told apart from tags nobody uses
+		used, err := c.tagRepository.IsUsed(ctx, ownerID, sources)
+		if err != nil {
+			return types.TagChange{}, queryStatus(ctx, err), queryError(ctx, err)
+		}
+		if used {
+			return types.TagChange{}, http.StatusConflict, errors.New("tag is used only on expenses locked by the approval workflow")
+		}
+		return types.TagChange{}, http.StatusNotFound, errors.New("tag not found")
+	}
+
+	return types.TagChange{Name: target, Updated: updated}, 0, nil
+}
+
+// normalizeTag is a function to lower case a tag and collapse its whitespace
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// normalizeTags is a function to normalize tags dropping blank and duplicate ones while keeping their order
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
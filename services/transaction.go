@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/repositories"
+)
+
+// txRetryDelay is a delay before the first retry of a transaction, it grows with every attempt
+const txRetryDelay = 10 * time.Millisecond
+
+// serializableTx is a transaction option making PostgreSQL abort a transaction that races a concurrent one
+// with a serialization failure, so runInTx runs it again instead of committing a lost update
+var serializableTx = &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+// runInTx is a function to run fn in a transaction begun by begin and commit it, the transaction is rolled back
+// when fn fails and run again from the start when it fails on a serialization failure or a deadlock,
+// it stops waiting for the next attempt when ctx is done
+func runInTx(ctx context.Context, begin func() (*sql.Tx, error), fn func(tx *sql.Tx) error) error {
+	attempts := configs.TxMaxAttempts()
+
+	for attempt := 1; ; attempt++ {
+		err := runOnce(begin, fn)
+		if err == nil || attempt >= attempts || !repositories.IsRetryable(err) {
+			return err
+		}
+
+		delay := time.NewTimer(time.Duration(attempt) * txRetryDelay)
+		select {
+		case <-ctx.Done():
+			delay.Stop()
+			return err
+		case <-delay.C:
+		}
+	}
+}
+
+// runOnce is a function to run fn in one transaction committing it when fn succeeds
+func runOnce(begin func() (*sql.Tx, error), fn func(tx *sql.Tx) error) error {
+	tx, err := begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// WithTx is a function to run fn with a copy of the service whose expense, category and attachment queries share
+// one serializable transaction, fn may run more than once when the transaction is retried so it must not keep
+// state between runs, a service already in a transaction runs fn in it
+func (c *ExpenseService) WithTx(ctx context.Context, fn func(txService *ExpenseService) error) error {
+	if c.expenseRepository.InTx() {
+		return fn(c)
+	}
+
+	begin := func() (*sql.Tx, error) {
+		return c.expenseRepository.Begin(ctx, serializableTx)
+	}
+
+	return runInTx(ctx, begin, func(tx *sql.Tx) error {
+		return fn(&ExpenseService{
+			expenseRepository:    c.expenseRepository.WithTx(tx),
+			categoryRepository:   c.categoryRepository.WithTx(tx),
+			attachmentRepository: c.attachmentRepository.WithTx(tx),
+			storage:              c.storage,
+		})
+	})
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/walkmanrd/assessment/auth"
+	"github.com/walkmanrd/assessment/models"
+	"github.com/walkmanrd/assessment/repositories"
+)
+
+// ErrUnauthorized is an error for credentials that do not resolve to a user
+var ErrUnauthorized = errors.New("unauthorized")
+
+// UserService is a struct for user service
+type UserService struct {
+	userRepository repositories.UserRepository
+	verifier       *auth.Verifier
+}
+
+// NewUserService is a function to create new user service, a nil verifier uses auth.DefaultVerifier
+func NewUserService(userRepository repositories.UserRepository, verifier *auth.Verifier) *UserService {
+	return &UserService{
+		userRepository: userRepository,
+		verifier:       verifier,
+	}
+}
+
+// Authenticate is a service function to resolve an Authorization header value into a user
+func (c *UserService) Authenticate(ctx context.Context, authorization string) (models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx, opRead)
+	defer cancel()
+
+	key := strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	if key == "" {
+		return models.User{}, ErrUnauthorized
+	}
+
+	if auth.LooksLikeJWT(key) {
+		return c.authenticateJWT(ctx, key)
+	}
+
+	// AUTH_TOKEN keeps working for clients from before users existed
+	legacyToken := os.Getenv("AUTH_TOKEN")
+	if legacyToken != "" && subtle.ConstantTimeCompare([]byte(key), []byte(legacyToken)) == 1 {
+		return c.userRepository.FindOne(ctx, repositories.LegacyUserID)
+	}
+
+	user, err := c.userRepository.FindByAPIKeyHash(ctx, auth.HashAPIKey(key))
+	if err == sql.ErrNoRows {
+		return models.User{}, ErrUnauthorized
+	}
+
+	return user, err
+}
+
+// authenticateJWT is a function to resolve a signed JWT into the user of its subject with the roles it claims
+func (c *UserService) authenticateJWT(ctx context.Context, token string) (models.User, error) {
+	verifier := c.verifier
+	if verifier == nil {
+		defaultVerifier, err := auth.DefaultVerifier()
+		if err != nil {
+			return models.User{}, err
+		}
+		verifier = defaultVerifier
+	}
+	if verifier == nil {
+		return models.User{}, ErrUnauthorized
+	}
+
+	claims, err := verifier.Verify(token)
+	if err != nil {
+		return models.User{}, ErrUnauthorized
+	}
+
+	user, err := c.userRepository.FindOrCreateBySubject(ctx, claims.Subject)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.Roles = claims.Roles
+
+	return user, nil
+}
+
+// Create is a service function to create a new user with roles and return its API key
+func (c *UserService) Create(ctx context.Context, name string, roles []string) (models.User, string, error) {
+	ctx, cancel := withQueryTimeout(ctx, opWrite)
+	defer cancel()
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	user, err := c.userRepository.Create(ctx, name, auth.HashAPIKey(key), roles)
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	return user, key, nil
+}
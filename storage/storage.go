@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/walkmanrd/assessment/configs"
+)
+
+// ErrNotFound is an error for a key without a stored object
+var ErrNotFound = errors.New("object not found")
+
+// Storage is an interface for a blob store keeping attachment content by key
+type Storage interface {
+	Put(key string, content io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+var (
+	defaultStorageOnce sync.Once
+	defaultStorage     Storage
+	defaultStorageErr  error
+)
+
+// NewFromConfig is a function to create new storage from storage settings
+func NewFromConfig(config configs.StorageConfig) (Storage, error) {
+	switch config.Driver {
+	case "filesystem":
+		return NewFileSystem(config.Dir), nil
+	case "s3":
+		if config.S3Endpoint == "" || config.S3Bucket == "" {
+			return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required by the s3 storage driver")
+		}
+		return NewS3(S3Options{
+			Endpoint:  config.S3Endpoint,
+			Region:    config.S3Region,
+			Bucket:    config.S3Bucket,
+			AccessKey: config.S3AccessKey,
+			SecretKey: config.S3SecretKey,
+			PathStyle: config.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", config.Driver)
+	}
+}
+
+// Default is a function to get the storage built once from the environment
+func Default() (Storage, error) {
+	defaultStorageOnce.Do(func() {
+		defaultStorage, defaultStorageErr = NewFromConfig(configs.Storage())
+	})
+	return defaultStorage, defaultStorageErr
+}
@@ -0,0 +1,7 @@
+package types
+
+// Actor is a type for the user making a change and the request it was made in, recorded on audit events
+type Actor struct {
+	ID        string
+	RequestID string
+}
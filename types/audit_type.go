@@ -0,0 +1,22 @@
+package types
+
+import "github.com/walkmanrd/assessment/models"
+
+// AuditQuery is a type for audit event list query parameters
+type AuditQuery struct {
+	Limit     int    `query:"limit" validate:"omitempty,min=1,max=200"`
+	Offset    int    `query:"offset" validate:"omitempty,min=0"`
+	ExpenseID string `query:"expense_id" validate:"omitempty,numeric"`
+	OwnerID   string `query:"owner_id" validate:"omitempty,numeric"`
+	ActorID   string `query:"actor_id" validate:"omitempty,numeric"`
+	Action    string `query:"action" validate:"omitempty,oneof=create update delete restore transition purge"`
+	RequestID string `query:"request_id"`
+	From      string `query:"from" validate:"omitempty,date"`
+	To        string `query:"to" validate:"omitempty,date"`
+}
+
+// AuditEventList is a type for a page of audit events newest first with the number of matching events
+type AuditEventList struct {
+	Data  []models.AuditEvent `json:"data"`
+	Total int                 `json:"total"`
+}
@@ -0,0 +1,32 @@
+package types
+
+import "github.com/walkmanrd/assessment/money"
+
+// BudgetRequest is a type for budget request, a budget is scoped by either a tag or a category
+type BudgetRequest struct {
+	Name       string       `json:"name" validate:"required"`
+	Amount     money.Amount `json:"amount" validate:"required,gt=0"`
+	Currency   string       `json:"currency" validate:"omitempty,iso4217"`
+	Period     string       `json:"period" validate:"required,oneof=weekly monthly quarterly yearly"`
+	Tag        *string      `json:"tag"`
+	CategoryID *string      `json:"category_id" validate:"omitempty,numeric"`
+	Thresholds []int64      `json:"thresholds" validate:"omitempty,max=10,dive,min=1,max=1000"`
+}
+
+// BudgetStatusQuery is a type for budget status query parameters
+type BudgetStatusQuery struct {
+	Date string `query:"date" validate:"omitempty,date"`
+}
+
+// BudgetStatus is a type for the consumption of a budget in the period containing a date
+type BudgetStatus struct {
+	BudgetID    string       `json:"budget_id"`
+	PeriodStart string       `json:"period_start"`
+	PeriodEnd   string       `json:"period_end"`
+	Amount      money.Amount `json:"amount"`
+	Spent       money.Amount `json:"spent"`
+	Remaining   money.Amount `json:"remaining"`
+	Percent     float64      `json:"percent"`
+	Count       int          `json:"count"`
+	Reached     []int64      `json:"reached"`
+}
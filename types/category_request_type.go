@@ -0,0 +1,7 @@
+package types
+
+// CategoryRequest is a type for category request
+type CategoryRequest struct {
+	Name     string  `json:"name" validate:"required,max=100"`
+	ParentID *string `json:"parent_id" validate:"omitempty,numeric"`
+}
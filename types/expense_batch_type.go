@@ -0,0 +1,33 @@
+package types
+
+import "github.com/walkmanrd/assessment/models"
+
+// ExpenseBatchRequest is a type for a batch of expense operations,
+// an atomic batch applies all operations in one transaction or none of them
+type ExpenseBatchRequest struct {
+	Mode       string                  `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Operations []ExpenseBatchOperation `json:"operations" validate:"required,min=1,max=100"`
+}
+
+// ExpenseBatchOperation is a type for one create, update or delete operation of a batch,
+// an update with a version only applies to that version of the expense
+type ExpenseBatchOperation struct {
+	Op      string          `json:"op"`
+	ID      string          `json:"id"`
+	Version *int            `json:"version"`
+	Expense *ExpenseRequest `json:"expense"`
+}
+
+// ExpenseBatchResult is a type for the outcome of one batch operation
+type ExpenseBatchResult struct {
+	Index   int             `json:"index"`
+	Status  int             `json:"status"`
+	Expense *models.Expense `json:"expense,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// ExpenseBatchResponse is a type for the outcomes of a batch
+type ExpenseBatchResponse struct {
+	Mode    string               `json:"mode"`
+	Results []ExpenseBatchResult `json:"results"`
+}
@@ -0,0 +1,28 @@
+package types
+
+import "github.com/walkmanrd/assessment/models"
+
+// ExpenseImportQuery is a type for expense import query parameters,
+// each map entry is a field and the CSV header it is read from as field:header
+type ExpenseImportQuery struct {
+	Delimiter    string   `query:"delimiter" validate:"omitempty,len=1"`
+	TagDelimiter string   `query:"tag_delimiter" validate:"omitempty,len=1"`
+	Map          []string `query:"map"`
+	DryRun       bool     `query:"dry_run"`
+}
+
+// ExpenseImportRow is a type for the outcome of one imported CSV row
+type ExpenseImportRow struct {
+	Row     int             `json:"row"`
+	Expense *models.Expense `json:"expense,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// ExpenseImportReport is a type for the outcome of an expense import
+type ExpenseImportReport struct {
+	DryRun    bool               `json:"dry_run"`
+	Total     int                `json:"total"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Rows      []ExpenseImportRow `json:"rows"`
+}
@@ -0,0 +1,10 @@
+package types
+
+import "github.com/walkmanrd/assessment/models"
+
+// ExpenseList is a type for a page of expenses
+type ExpenseList struct {
+	Data       []models.Expense `json:"data"`
+	NextCursor string           `json:"next_cursor"`
+	Total      int              `json:"total"`
+}
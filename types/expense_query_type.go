@@ -0,0 +1,29 @@
+package types
+
+import "github.com/walkmanrd/assessment/money"
+
+// ExpenseQuery is a type for expense list query parameters
+type ExpenseQuery struct {
+	Limit     int           `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor    string        `query:"cursor"`
+	Tags      []string      `query:"tag"`
+	TagMode   string        `query:"tag_mode" validate:"omitempty,oneof=any all"`
+	MinAmount *money.Amount `query:"min_amount"`
+	MaxAmount *money.Amount `query:"max_amount"`
+	Currency  string        `query:"currency"`
+	Title     string        `query:"title"`
+	Status    string        `query:"status" validate:"omitempty,oneof=draft submitted approved rejected reimbursed"`
+	Category  string        `query:"category_id" validate:"omitempty,numeric"`
+	SpentFrom string        `query:"spent_from" validate:"omitempty,date"`
+	SpentTo   string        `query:"spent_to" validate:"omitempty,date"`
+	Sort      string        `query:"sort" validate:"omitempty,oneof=id amount title"`
+	Order     string        `query:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+// ExpenseCursor is a type for the decoded position of an expense list cursor
+type ExpenseCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
@@ -1,9 +1,14 @@
 package types
 
+import "github.com/walkmanrd/assessment/money"
+
 // ExpenseRequest is a type for expense request
 type ExpenseRequest struct {
-	Title  string   `json:"title" validate:"required"`
-	Amount float64  `json:"amount" validate:"required,number"`
-	Note   string   `json:"note" validate:"required"`
-	Tags   []string `json:"tags" validate:"required,min=1"`
+	Title      string       `json:"title" validate:"required"`
+	Amount     money.Amount `json:"amount" validate:"required"`
+	Currency   string       `json:"currency" validate:"omitempty,iso4217"`
+	Note       string       `json:"note" validate:"required"`
+	Tags       []string     `json:"tags" validate:"required,min=1"`
+	CategoryID *string      `json:"category_id" validate:"omitempty,numeric"`
+	SpentAt    string       `json:"spent_at" validate:"omitempty,date"`
 }
@@ -0,0 +1,35 @@
+package types
+
+import "github.com/walkmanrd/assessment/models"
+
+// ExpenseSearchQuery is a type for expense search query parameters
+type ExpenseSearchQuery struct {
+	Q         string   `query:"q" validate:"required,max=200"`
+	Limit     int      `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset    int      `query:"offset" validate:"omitempty,min=0"`
+	Tags      []string `query:"tag"`
+	Category  string   `query:"category_id" validate:"omitempty,numeric"`
+	SpentFrom string   `query:"spent_from" validate:"omitempty,date"`
+	SpentTo   string   `query:"spent_to" validate:"omitempty,date"`
+}
+
+// ExpenseHighlight is a type for the parts of an expense matching a search as HTML, the text is escaped
+// and matches are wrapped in <mark> tags
+type ExpenseHighlight struct {
+	Title string   `json:"title"`
+	Note  string   `json:"note"`
+	Tags  []string `json:"tags"`
+}
+
+// ExpenseSearchHit is a type for an expense found by a search
+type ExpenseSearchHit struct {
+	Expense   models.Expense   `json:"expense"`
+	Rank      float64          `json:"rank"`
+	Highlight ExpenseHighlight `json:"highlight"`
+}
+
+// ExpenseSearchResult is a type for a page of expense search hits ordered by rank
+type ExpenseSearchResult struct {
+	Data  []ExpenseSearchHit `json:"data"`
+	Total int                `json:"total"`
+}
@@ -0,0 +1,6 @@
+package types
+
+// ExpenseTransitionRequest is a type for the optional comment of an expense status change
+type ExpenseTransitionRequest struct {
+	Comment string `json:"comment" validate:"max=1000"`
+}
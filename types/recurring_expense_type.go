@@ -0,0 +1,28 @@
+package types
+
+import "github.com/walkmanrd/assessment/money"
+
+// RecurringExpenseRequest is a type for recurring expense request
+type RecurringExpenseRequest struct {
+	Title      string       `json:"title" validate:"required"`
+	Amount     money.Amount `json:"amount" validate:"required"`
+	Currency   string       `json:"currency" validate:"omitempty,iso4217"`
+	Note       string       `json:"note" validate:"required"`
+	Tags       []string     `json:"tags" validate:"required,min=1"`
+	CategoryID *string      `json:"category_id" validate:"omitempty,numeric"`
+	Frequency  string       `json:"frequency" validate:"required,oneof=daily weekly monthly yearly"`
+	Interval   int          `json:"interval" validate:"omitempty,min=1,max=1000"`
+	StartsOn   string       `json:"starts_on" validate:"required,date"`
+	EndsOn     *string      `json:"ends_on" validate:"omitempty,date"`
+	Count      *int         `json:"count" validate:"omitempty,min=1"`
+}
+
+// RecurringExpensePreviewQuery is a type for recurring expense preview query parameters
+type RecurringExpensePreviewQuery struct {
+	Count int `query:"count" validate:"omitempty,min=1,max=100"`
+}
+
+// RecurringExpensePreview is a type for the upcoming occurrence dates of a recurring expense
+type RecurringExpensePreview struct {
+	Dates []string `json:"dates"`
+}
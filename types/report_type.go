@@ -0,0 +1,21 @@
+package types
+
+import "github.com/walkmanrd/assessment/models"
+
+// ReportQuery is a type for report query parameters
+type ReportQuery struct {
+	GroupBy  string   `query:"group_by" validate:"required,oneof=tag category month week day currency"`
+	From     string   `query:"from" validate:"omitempty,date"`
+	To       string   `query:"to" validate:"omitempty,date"`
+	Tags     []string `query:"tag"`
+	TagMode  string   `query:"tag_mode" validate:"omitempty,oneof=any all"`
+	Currency string   `query:"currency"`
+}
+
+// Report is a type for aggregated expenses grouped by a dimension
+type Report struct {
+	GroupBy string               `json:"group_by"`
+	From    string               `json:"from,omitempty"`
+	To      string               `json:"to,omitempty"`
+	Groups  []models.ReportGroup `json:"groups"`
+}
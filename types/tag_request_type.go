@@ -0,0 +1,18 @@
+package types
+
+// TagRenameRequest is a type for tag rename request
+type TagRenameRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// TagMergeRequest is a type for tag merge request
+type TagMergeRequest struct {
+	Sources []string `json:"sources" validate:"required,min=1"`
+	Target  string   `json:"target" validate:"required"`
+}
+
+// TagChange is a type for the result of renaming or merging tags
+type TagChange struct {
+	Name    string `json:"name"`
+	Updated int    `json:"updated"`
+}
@@ -1,9 +1,14 @@
 package validators
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
-	"net/http"
+	"github.com/walkmanrd/assessment/configs"
+	"github.com/walkmanrd/assessment/money"
+	"github.com/walkmanrd/assessment/types"
 )
 
 // CustomValidator is a struct for custom validator
@@ -11,6 +16,16 @@ type CustomValidator struct {
 	Validator *validator.Validate
 }
 
+// NewCustomValidator is a function to create new custom validator with the custom validations registered
+func NewCustomValidator() *CustomValidator {
+	v := validator.New()
+	v.RegisterValidation("iso4217", isCurrency)
+	v.RegisterValidation("date", isDate)
+	v.RegisterStructValidation(expenseRequestValidation, types.ExpenseRequest{})
+
+	return &CustomValidator{Validator: v}
+}
+
 // Validate is a function to validate request
 func (cv *CustomValidator) Validate(i interface{}) error {
 	if err := cv.Validator.Struct(i); err != nil {
@@ -18,3 +33,29 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 	}
 	return nil
 }
+
+// isCurrency is a validation function for an ISO 4217 currency code
+func isCurrency(fl validator.FieldLevel) bool {
+	return money.IsCurrency(money.NormalizeCurrency(fl.Field().String()))
+}
+
+// isDate is a validation function for a calendar date formatted as YYYY-MM-DD
+func isDate(fl validator.FieldLevel) bool {
+	_, err := time.Parse("2006-01-02", fl.Field().String())
+	return err == nil
+}
+
+// expenseRequestValidation is a struct level validation that the amount fits the currency minor unit
+func expenseRequestValidation(sl validator.StructLevel) {
+	expenseRequest := sl.Current().Interface().(types.ExpenseRequest)
+
+	currency := money.NormalizeCurrency(expenseRequest.Currency)
+	if currency == "" {
+		currency = configs.DefaultCurrency()
+	}
+
+	exponent, ok := money.Exponent(currency)
+	if ok && expenseRequest.Amount.Decimals() > exponent {
+		sl.ReportError(expenseRequest.Amount, "Amount", "amount", "decimals", currency)
+	}
+}
@@ -0,0 +1,57 @@
+// Package workers holds the background jobs started with the server.
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Materializer is an interface for a job creating the expenses due by a time
+type Materializer interface {
+	MaterializeDue(ctx context.Context, now time.Time) (int, error)
+}
+
+// RecurringExpenseWorker is a struct for a worker materializing due recurring expenses on an interval
+type RecurringExpenseWorker struct {
+	materializer Materializer
+	interval     time.Duration
+	now          func() time.Time
+}
+
+// NewRecurringExpenseWorker is a function to create new recurring expense worker
+func NewRecurringExpenseWorker(materializer Materializer, interval time.Duration) *RecurringExpenseWorker {
+	return &RecurringExpenseWorker{
+		materializer: materializer,
+		interval:     interval,
+		now:          time.Now,
+	}
+}
+
+// Run is a function to materialize due recurring expenses right away and then on every interval until ctx is done,
+// a failed run is logged and retried on the next interval
+func (w *RecurringExpenseWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick is a function to run the materializer once, a run is cut short when ctx is done
+func (w *RecurringExpenseWorker) tick(ctx context.Context) {
+	created, err := w.materializer.MaterializeDue(ctx, w.now())
+	if err != nil {
+		log.Println("can't materialize recurring expenses", err)
+	}
+	if created > 0 {
+		log.Printf("materialized %d recurring expenses\n", created)
+	}
+}
@@ -0,0 +1,142 @@
+//go:build unit
+
+package workers
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkmanrd/assessment/repositories"
+	"github.com/walkmanrd/assessment/services"
+)
+
+var recurringExpenseColumns = []string{"id", "title", "amount", "currency", "note", "tags", "category_id", "frequency", "interval_count",
+	"starts_on", "ends_on", "max_count", "position", "next_on", "paused", "created_at", "updated_at"}
+
+func TestMaterializeDue(t *testing.T) {
+	t.Setenv("APP_TIMEZONE", "UTC")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	createdAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// weekly from 2022-01-03 with three occurrences, the second already materialized by another replica
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE NOT paused AND next_on <= $1`) + `(.|\n)*` + regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WithArgs("2022-01-20").
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+			AddRow("1", "gym", 900, "THB", "weekly class", `{"health"}`, nil, "weekly", 1, "2022-01-03", nil, 3, 0, "2022-01-03", false, createdAt, createdAt))
+	for _, occurrence := range []struct {
+		date     string
+		affected int64
+	}{{"2022-01-03", 1}, {"2022-01-10", 0}, {"2022-01-17", 1}} {
+		mock.ExpectExec(regexp.QuoteMeta(`ON CONFLICT (recurring_expense_id, spent_at) DO NOTHING`)).
+			WithArgs("1", occurrence.date).
+			WillReturnResult(sqlmock.NewResult(0, occurrence.affected))
+	}
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE recurring_expenses SET position = $2, next_on = $3, failures = 0, retry_at = NULL WHERE id = $1`)).
+		WithArgs("1", 3, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns))
+	mock.ExpectRollback()
+
+	service := services.NewRecurringExpenseService(*repositories.NewRecurringExpenseRepository(db), *repositories.NewCategoryRepository(db))
+	created, err := service.MaterializeDue(context.Background(), time.Date(2022, 1, 20, 12, 0, 0, 0, time.UTC))
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, created)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMaterializeDueSetsFailingScheduleAside(t *testing.T) {
+	t.Setenv("APP_TIMEZONE", "UTC")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error '%s' was not expected when opening a stub database connection", err)
+	}
+	createdAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	claimSQL := regexp.QuoteMeta(`AND (retry_at IS NULL OR retry_at <= NOW())`) + `(.|\n)*` + regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)
+	insertSQL := regexp.QuoteMeta(`ON CONFLICT (recurring_expense_id, spent_at) DO NOTHING`)
+
+	// the schedule due the longest fails, the next one still materializes in the same run
+	mock.ExpectBegin()
+	mock.ExpectQuery(claimSQL).
+		WithArgs("2022-01-20").
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+			AddRow("1", "gym", 900, "THB", "", `{"health"}`, nil, "weekly", 1, "2022-01-03", nil, 1, 0, "2022-01-03", false, createdAt, createdAt))
+	mock.ExpectExec(insertSQL).
+		WithArgs("1", "2022-01-03").
+		WillReturnError(errors.New("category is gone"))
+	mock.ExpectRollback()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE recurring_expenses SET failures = failures + 1`)).
+		WithArgs("1", 60.0, 3600.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(claimSQL).
+		WithArgs("2022-01-20").
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+			AddRow("2", "rent", 9000, "THB", "", `{"home"}`, nil, "monthly", 1, "2022-01-05", nil, 1, 0, "2022-01-05", false, createdAt, createdAt))
+	mock.ExpectExec(insertSQL).
+		WithArgs("2", "2022-01-05").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE recurring_expenses SET position = $2`)).
+		WithArgs("2", 1, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(claimSQL).
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns))
+	mock.ExpectRollback()
+
+	service := services.NewRecurringExpenseService(*repositories.NewRecurringExpenseRepository(db), *repositories.NewCategoryRepository(db))
+	created, err := service.MaterializeDue(context.Background(), time.Date(2022, 1, 20, 12, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, 1, created)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "recurring expense 1: category is gone")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type countingMaterializer struct {
+	runs chan time.Time
+}
+
+func (m countingMaterializer) MaterializeDue(ctx context.Context, now time.Time) (int, error) {
+	m.runs <- now
+	return 0, nil
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	materializer := countingMaterializer{runs: make(chan time.Time, 10)}
+	worker := NewRecurringExpenseWorker(materializer, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	<-materializer.runs
+	<-materializer.runs
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after its context was cancelled")
+	}
+}